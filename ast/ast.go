@@ -47,6 +47,9 @@ type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
 	Value Expression
+
+	// Exported bindings of a module are visible to its importers
+	Exported bool
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -58,6 +61,9 @@ func (ls *LetStatement) TokenLiteral() string {
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
+	if ls.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
 	out.WriteString(" = ")
@@ -341,6 +347,100 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+type SliceExpression struct {
+	Token token.Token // `[` token
+	Left  Expression
+	Start Expression // optional
+	End   Expression // optional
+	Step  Expression // optional
+}
+
+func (se *SliceExpression) expressionNode() {}
+
+func (se *SliceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// SpreadExpression is `...value` inside array literals, hash literals and call
+// arguments
+type SpreadExpression struct {
+	Token token.Token // `...` token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {}
+
+func (se *SpreadExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
+type MemberExpression struct {
+	Token  token.Token // `.` token
+	Object Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(me.Object.String())
+	out.WriteString(".")
+	out.WriteString(me.Member.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// ImportExpression evaluates to the module loaded from Path, e.g.
+// `import "lib/math"`
+type ImportExpression struct {
+	Token token.Token // the IMPORT token
+	Path  string
+}
+
+func (ie *ImportExpression) expressionNode() {}
+
+func (ie *ImportExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+func (ie *ImportExpression) String() string {
+	return "import \"" + ie.Path + "\""
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -356,6 +456,33 @@ func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
 }
 
+// InterpolatedString is a string literal with `${expr}` interpolations, text
+// between them is kept as StringLiteral parts
+type InterpolatedString struct {
+	Token token.Token // the TEMPLATE token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode() {}
+
+func (is *InterpolatedString) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(text.Value)
+			continue
+		}
+		out.WriteString("${" + part.String() + "}")
+	}
+
+	return out.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
@@ -382,9 +509,15 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// HashPair is either `key: value` or `...spread` with a nil Value
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
 type HashLiteral struct {
 	Token token.Token // `{` token
-	Pairs map[Expression]Expression
+	Pairs []HashPair  // in source order
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -397,8 +530,12 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		if pair.Value == nil {
+			pairs = append(pairs, pair.Key.String())
+			continue
+		}
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
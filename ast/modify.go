@@ -24,6 +24,29 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Index, _ = Modify(node.Index, modifier).(Expression)
 
+	case *SliceExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		if node.Start != nil {
+			node.Start, _ = Modify(node.Start, modifier).(Expression)
+		}
+		if node.End != nil {
+			node.End, _ = Modify(node.End, modifier).(Expression)
+		}
+		if node.Step != nil {
+			node.Step, _ = Modify(node.Step, modifier).(Expression)
+		}
+
+	case *InterpolatedString:
+		for i, part := range node.Parts {
+			node.Parts[i], _ = Modify(part, modifier).(Expression)
+		}
+
+	case *SpreadExpression:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+	case *MemberExpression:
+		node.Object, _ = Modify(node.Object, modifier).(Expression)
+
 	case *IfExpression:
 		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
 		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
@@ -54,13 +77,12 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		}
 
 	case *HashLiteral:
-		newPairs := make(map[Expression]Expression)
-		for key, val := range node.Pairs {
-			newKey, _ := Modify(key, modifier).(Expression)
-			newVal, _ := Modify(val, modifier).(Expression)
-			newPairs[newKey] = newVal
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key, _ = Modify(pair.Key, modifier).(Expression)
+			if pair.Value != nil {
+				node.Pairs[i].Value, _ = Modify(pair.Value, modifier).(Expression)
+			}
 		}
-		node.Pairs = newPairs
 	}
 
 	return modifier(node)
@@ -122,6 +122,34 @@ func TestModify(t *testing.T) {
 			&ArrayLiteral{Elements: []Expression{one(), one()}},
 			&ArrayLiteral{Elements: []Expression{two(), two()}},
 		},
+		{
+			&SliceExpression{Left: one(), Start: one(), End: one()},
+			&SliceExpression{Left: two(), Start: two(), End: two()},
+		},
+		{
+			&SliceExpression{Left: one(), End: one()},
+			&SliceExpression{Left: two(), End: two()},
+		},
+		{
+			&SliceExpression{Left: one(), Step: one()},
+			&SliceExpression{Left: two(), Step: two()},
+		},
+		{
+			&InterpolatedString{Parts: []Expression{&StringLiteral{Value: "a"}, one()}},
+			&InterpolatedString{Parts: []Expression{&StringLiteral{Value: "a"}, two()}},
+		},
+		{
+			&SpreadExpression{Value: one()},
+			&SpreadExpression{Value: two()},
+		},
+		{
+			&HashLiteral{Pairs: []HashPair{{Key: &SpreadExpression{Value: one()}}}},
+			&HashLiteral{Pairs: []HashPair{{Key: &SpreadExpression{Value: two()}}}},
+		},
+		{
+			&MemberExpression{Object: one(), Member: &Identifier{Value: "x"}},
+			&MemberExpression{Object: two(), Member: &Identifier{Value: "x"}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -133,21 +161,21 @@ func TestModify(t *testing.T) {
 	}
 
 	hashLiteral := &HashLiteral{
-		Pairs: map[Expression]Expression{
-			one(): one(),
-			one(): one(),
+		Pairs: []HashPair{
+			{Key: one(), Value: one()},
+			{Key: one(), Value: one()},
 		},
 	}
 
 	Modify(hashLiteral, turnOneIntoTwo)
 
-	for key, val := range hashLiteral.Pairs {
-		key, _ := key.(*IntegerLiteral)
+	for _, pair := range hashLiteral.Pairs {
+		key, _ := pair.Key.(*IntegerLiteral)
 		if key.Value != 2 {
 			t.Errorf("want key 2, got %d", key.Value)
 		}
 
-		val, _ := val.(*IntegerLiteral)
+		val, _ := pair.Value.(*IntegerLiteral)
 		if val.Value != 2 {
 			t.Errorf("want val 2, got %d", val.Value)
 		}
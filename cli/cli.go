@@ -0,0 +1,180 @@
+// Package cli implements the monkey command.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lancelote/writing-an-interpreter-in-go/monkey"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"github.com/lancelote/writing-an-interpreter-in-go/repl"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+const usage = `usage:
+  monkey                                  start the REPL or run a script piped to stdin
+  monkey [--fs DIR] [run] FILE [ARGS...]  run a script, "-" reads it from stdin
+  monkey [--fs DIR] -e SOURCE [ARGS...]   evaluate the source and print the result
+
+options:
+  --fs DIR   let scripts read and write files in DIR, they have no file access
+             otherwise
+
+scripts starting with "#!/usr/bin/env monkey" can be executed directly
+`
+
+// exit codes used unless the script calls `exit`
+const (
+	ExitError = 1 // parse or runtime error
+	ExitUsage = 2 // invalid command line
+)
+
+// Streams are standard streams of the command.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Interactive is true if Stdin is a terminal, the REPL is only started
+	// for a terminal
+	Interactive bool
+}
+
+// Main runs the command with the arguments following the program name,
+// returns the exit code.
+func Main(args []string, streams Streams) int {
+	fsDir := ""
+	if len(args) > 0 && args[0] == "--fs" {
+		if len(args) < 2 {
+			return usageError(streams, "missing directory for --fs")
+		}
+		fsDir, args = args[1], args[2:]
+	}
+
+	if len(args) == 0 {
+		if streams.Interactive {
+			if fsDir != "" {
+				return usageError(streams, "--fs is only supported for scripts")
+			}
+			return startREPL(streams)
+		}
+		return runStdin(nil, fsDir, streams)
+	}
+
+	switch args[0] {
+
+	case "run":
+		if len(args) < 2 {
+			return usageError(streams, "missing script file")
+		}
+		return runFile(args[1], args[2:], fsDir, streams)
+
+	case "-e":
+		if len(args) < 2 {
+			return usageError(streams, "missing source to evaluate")
+		}
+
+		return run(args[1], ".", args[2:], fsDir, streams, true)
+
+	case "-h", "--help", "help":
+		io.WriteString(streams.Stdout, usage)
+		return 0
+
+	default:
+		if args[0] != "-" && strings.HasPrefix(args[0], "-") {
+			return usageError(streams, fmt.Sprintf("unknown option %q", args[0]))
+		}
+
+		// shebang scripts are run as `monkey FILE ARGS...`
+		return runFile(args[0], args[1:], fsDir, streams)
+	}
+}
+
+// runFile runs the script file, "-" means stdin
+func runFile(path string, args []string, fsDir string, streams Streams) int {
+	if path == "-" {
+		return runStdin(args, fsDir, streams)
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "cannot read script: %s\n", err)
+		return ExitError
+	}
+
+	return run(string(source), filepath.Dir(path), args, fsDir, streams, false)
+}
+
+func startREPL(streams Streams) int {
+	user, err := user.Current()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(streams.Stdout, "hello %s! this is the Monkey programming language!\n", user.Username)
+	fmt.Fprintf(streams.Stdout, "feel free to type in commands\n")
+	return repl.Start(streams.Stdin, streams.Stdout)
+}
+
+func runStdin(args []string, fsDir string, streams Streams) int {
+	source, err := io.ReadAll(streams.Stdin)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "cannot read script: %s\n", err)
+		return ExitError
+	}
+
+	return run(string(source), ".", args, fsDir, streams, false)
+}
+
+// run evaluates the script, modules are imported from dir and files are only
+// accessible in fsDir if it's not empty. The result is only printed if
+// printResult is true and it's not null.
+func run(source, dir string, args []string, fsDir string, streams Streams, printResult bool) int {
+	options := []monkey.Option{
+		monkey.WithStdout(streams.Stdout),
+		monkey.WithStderr(streams.Stderr),
+		monkey.WithArgs(args...),
+		monkey.WithModules(os.DirFS(dir)),
+	}
+
+	if fsDir != "" {
+		fsys, err := monkey.OpenDir(fsDir)
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "cannot open file system: %s\n", err)
+			return ExitError
+		}
+		defer fsys.Close()
+		options = append(options, monkey.WithFS(fsys))
+	}
+
+	result, err := monkey.New(options...).Run(source)
+
+	var parseErr *monkey.ParseError
+	var exitErr *object.ExitError
+
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.As(err, &parseErr):
+		for _, msg := range parseErr.Errors {
+			fmt.Fprintf(streams.Stderr, "parse error: %s\n", msg)
+		}
+		return ExitError
+	case err != nil:
+		fmt.Fprintf(streams.Stderr, "error: %s\n", err)
+		return ExitError
+	}
+
+	if printResult && result != nil && result.Type() != object.NULL_OBJ {
+		fmt.Fprintln(streams.Stdout, result.Inspect())
+	}
+
+	return 0
+}
+
+func usageError(streams Streams, msg string) int {
+	fmt.Fprintf(streams.Stderr, "%s\n%s", msg, usage)
+	return ExitUsage
+}
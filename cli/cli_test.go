@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.mk":     "#!/usr/bin/env monkey\nlet util = import \"lib/util\"; puts(util.greet(first(args())));",
+		"lib/util.mk": `export let greet = fn(name) { "hello " + name };`,
+		"exit.mk":     `exit(len(args()))`,
+		"error.mk":    `puts("before"); 1 + true`,
+		"broken.mk":   `let = 1`,
+		"files.mk":    `write_file("out.txt", "saved"); puts(read_file("out.txt"))`,
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"run", filepath.Join(dir, "main.mk"), "monkey"}, "", 0, "hello monkey\n", ""},
+		{[]string{"run", filepath.Join(dir, "exit.mk"), "a", "b", "c"}, "", 3, "", ""},
+		// the kernel runs a shebang script as `monkey FILE ARGS...`
+		{[]string{filepath.Join(dir, "main.mk"), "shebang"}, "", 0, "hello shebang\n", ""},
+		{[]string{filepath.Join(dir, "exit.mk"), "a"}, "", 1, "", ""},
+		{[]string{filepath.Join(dir, "missing.mk")}, "", ExitError, "", "cannot read script"},
+		{[]string{"-", "y"}, `puts(args())`, 0, "[y]\n", ""},
+		{[]string{"run", filepath.Join(dir, "error.mk")}, "", ExitError, "before\n", "error: type mismatch: INTEGER + BOOLEAN\n"},
+		{[]string{"run", filepath.Join(dir, "broken.mk")}, "", ExitError, "", "parse error: want token IDENT, got =\n"},
+		{[]string{"run", filepath.Join(dir, "missing.mk")}, "", ExitError, "", "cannot read script"},
+		{[]string{"run", "-", "x"}, `puts(args())`, 0, "[x]\n", ""},
+		{[]string{"run"}, "", ExitUsage, "", "missing script file\n"},
+		{[]string{"-e", "1 + 2"}, "", 0, "3\n", ""},
+		{[]string{"-e", `puts("side effect")`}, "", 0, "side effect\n", ""},
+		{[]string{"-e", "args()", "a", "b"}, "", 0, "[a, b]\n", ""},
+		{[]string{"-e", "exit(4)"}, "", 4, "", ""},
+		{[]string{"-e"}, "", ExitUsage, "", "missing source to evaluate\n"},
+		{nil, "puts(1 + 1)", 0, "2\n", ""},
+		{[]string{"run", filepath.Join(dir, "files.mk")}, "", ExitError, "", "error: file system access is not allowed\n"},
+		{[]string{"--fs", dir, "run", filepath.Join(dir, "files.mk")}, "", 0, "saved\n", ""},
+		{[]string{"--fs", dir, "-e", `read_file("out.txt")`}, "", 0, "saved\n", ""},
+		{[]string{"--fs", filepath.Join(dir, "missing")}, "", ExitError, "", "cannot open file system"},
+		{[]string{"--fs"}, "", ExitUsage, "", "missing directory for --fs\n"},
+		{[]string{"-x"}, "", ExitUsage, "", "unknown option \"-x\"\n"},
+		{[]string{"--help"}, "", 0, usage, ""},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+
+		code := Main(tt.args, Streams{Stdin: strings.NewReader(tt.stdin), Stdout: &stdout, Stderr: &stderr})
+
+		if code != tt.code {
+			t.Errorf("%v: want exit code %d, got %d", tt.args, tt.code, code)
+		}
+
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: want stdout %q, got %q", tt.args, tt.stdout, stdout.String())
+		}
+
+		if !strings.HasPrefix(stderr.String(), tt.stderr) || (tt.stderr == "") != (stderr.Len() == 0) {
+			t.Errorf("%v: want stderr starting with %q, got %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
@@ -3,22 +3,44 @@ package evaluator
 import (
 	"fmt"
 	"github.com/lancelote/writing-an-interpreter-in-go/object"
-	"os"
+	"unicode/utf8"
 )
 
-var builtins = map[string]*object.Builtin{
+// builtins include the standard library groups, collectionBuiltins call back
+// into Eval and are added in init, see methods.go
+var builtins = withGroups(map[string]*object.Builtin{
+	"args": {
+		Name:   "args",
+		Doc:    "Returns command-line arguments of the script as an array of strings.",
+		Params: []object.ObjectType{},
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			return allocate(runtime, stringArray(runtime.Args))
+		},
+	},
 	"exit": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 0 {
-				return newError("`exit()` doesn't accept arguments")
+		Name: "exit",
+		Doc:  "Stops the program with an optional exit code.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError("`exit` accepts at most 1 argument, got %d", len(args))
+			}
+
+			if len(args) == 0 {
+				return runtime.Exit(0)
+			}
+
+			code, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to `exit` should be INTEGER, got %s", args[0].Type())
 			}
 
-			os.Exit(0)
-			return nil
+			return runtime.Exit(int(code.Value))
 		},
 	},
 	"first": {
-		Fn: func(args ...object.Object) object.Object {
+		Name: "first",
+		Doc:  "Returns the first element of an array or null if it's empty.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("`first` accepts 1 argument, got %d", len(args))
 			}
@@ -35,8 +57,25 @@ var builtins = map[string]*object.Builtin{
 			return NULL
 		},
 	},
+	"help": {
+		Name:   "help",
+		Doc:    "Describes a builtin function.",
+		Params: []object.ObjectType{object.BUILTIN_OBJ},
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			builtin := args[0].(*object.Builtin)
+
+			help := builtin.Signature()
+			if builtin.Doc != "" {
+				help += "\n" + builtin.Doc
+			}
+
+			return allocate(runtime, &object.String{Value: help})
+		},
+	},
 	"len": {
-		Fn: func(args ...object.Object) object.Object {
+		Name: "len",
+		Doc:  "Returns the number of runes in a string or elements in an array or a hash.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments, want 1, got %d", len(args))
 			}
@@ -47,7 +86,11 @@ var builtins = map[string]*object.Builtin{
 				return &object.Integer{Value: int64(len(arg.Elements))}
 
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				// runes, not bytes, to match string indexing and slicing
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+
+			case *object.Hash:
+				return &object.Integer{Value: int64(arg.Len())}
 
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
@@ -55,7 +98,9 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": {
-		Fn: func(args ...object.Object) object.Object {
+		Name: "last",
+		Doc:  "Returns the last element of an array or null if it's empty.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("`last` accepts 1 argument, got %d", len(args))
 			}
@@ -73,7 +118,9 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": {
-		Fn: func(args ...object.Object) object.Object {
+		Name: "push",
+		Doc:  "Returns a new array with the element appended.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 2 {
 				return newError("`push` accepts 2 arguments, got %d", len(args))
 			}
@@ -89,20 +136,24 @@ var builtins = map[string]*object.Builtin{
 			copy(newElements, arr.Elements)
 			newElements[length] = args[1]
 
-			return &object.Array{Elements: newElements}
+			return allocate(runtime, &object.Array{Elements: newElements})
 		},
 	},
 	"puts": {
-		Fn: func(args ...object.Object) object.Object {
+		Name: "puts",
+		Doc:  "Prints every argument on a separate line.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(runtime.Out(), arg.Inspect())
 			}
 
 			return NULL
 		},
 	},
 	"rest": {
-		Fn: func(args ...object.Object) object.Object {
+		Name: "rest",
+		Doc:  "Returns a new array without the first element or null if it's empty.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("`rest` accepts 1 argument, got %d", len(args))
 			}
@@ -116,10 +167,20 @@ var builtins = map[string]*object.Builtin{
 			if length > 0 {
 				newElements := make([]object.Object, length-1, length-1)
 				copy(newElements, arr.Elements[1:length])
-				return &object.Array{Elements: newElements}
+				return allocate(runtime, &object.Array{Elements: newElements})
 			}
 
 			return NULL
 		},
 	},
+}, stringBuiltins(), jsonBuiltins(), mathBuiltins(), regexBuiltins(), timeBuiltins(), fileBuiltins())
+
+// withGroups adds the builtin groups to builtins, later names win
+func withGroups(builtins map[string]*object.Builtin, groups ...[]*object.Builtin) map[string]*object.Builtin {
+	for _, group := range groups {
+		for _, builtin := range group {
+			builtins[builtin.Name] = builtin
+		}
+	}
+	return builtins
 }
@@ -0,0 +1,410 @@
+package evaluator
+
+import (
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"math"
+	"sort"
+)
+
+// collectionBuiltins call back into Monkey functions, so they are registered
+// in init, see methods.go
+func collectionBuiltins() []*object.Builtin {
+	return []*object.Builtin{
+		{
+			Name:   "map",
+			Doc:    "Returns a new array with the function applied to every element.",
+			Params: []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				arr := args[0].(*object.Array)
+
+				elements := make([]object.Object, len(arr.Elements))
+				for i, element := range arr.Elements {
+					mapped := applyFunction(runtime, args[1], []object.Object{element})
+					if isError(mapped) {
+						return mapped
+					}
+					elements[i] = mapped
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:   "filter",
+			Doc:    "Returns a new array with elements the function returns a truthy value for.",
+			Params: []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				arr := args[0].(*object.Array)
+
+				elements := []object.Object{}
+				for _, element := range arr.Elements {
+					keep := applyFunction(runtime, args[1], []object.Object{element})
+					if isError(keep) {
+						return keep
+					}
+					if isTruthy(keep) {
+						elements = append(elements, element)
+					}
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:     "reduce",
+			Doc:      "Combines elements with the function, starting with the initial value or the first element.",
+			Params:   []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ, object.ANY_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 3 {
+					return newError("`reduce` accepts at most 3 arguments, got %d", len(args))
+				}
+
+				elements := args[0].(*object.Array).Elements
+
+				var acc object.Object
+				if len(args) == 3 {
+					acc = args[2]
+				} else if len(elements) > 0 {
+					acc, elements = elements[0], elements[1:]
+				} else {
+					return newError("`reduce` of empty array with no initial value")
+				}
+
+				for _, element := range elements {
+					acc = applyFunction(runtime, args[1], []object.Object{acc, element})
+					if isError(acc) {
+						return acc
+					}
+				}
+
+				return acc
+			},
+		},
+		{
+			Name:   "each",
+			Doc:    "Calls the function with every element, returns null.",
+			Params: []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				for _, element := range args[0].(*object.Array).Elements {
+					result := applyFunction(runtime, args[1], []object.Object{element})
+					if isError(result) {
+						return result
+					}
+				}
+
+				return NULL
+			},
+		},
+		{
+			Name:   "any",
+			Doc:    "Reports whether the function returns a truthy value for any element.",
+			Params: []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				for _, element := range args[0].(*object.Array).Elements {
+					result := applyFunction(runtime, args[1], []object.Object{element})
+					if isError(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return TRUE
+					}
+				}
+
+				return FALSE
+			},
+		},
+		{
+			Name:   "all",
+			Doc:    "Reports whether the function returns a truthy value for every element.",
+			Params: []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				for _, element := range args[0].(*object.Array).Elements {
+					result := applyFunction(runtime, args[1], []object.Object{element})
+					if isError(result) {
+						return result
+					}
+					if !isTruthy(result) {
+						return FALSE
+					}
+				}
+
+				return TRUE
+			},
+		},
+		{
+			Name:   "find",
+			Doc:    "Returns the first element the function returns a truthy value for or null.",
+			Params: []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				for _, element := range args[0].(*object.Array).Elements {
+					result := applyFunction(runtime, args[1], []object.Object{element})
+					if isError(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return element
+					}
+				}
+
+				return NULL
+			},
+		},
+		{
+			Name:     "zip",
+			Doc:      "Returns an array of arrays grouping elements at the same index, as long as the shortest array.",
+			Params:   []object.ObjectType{object.ARRAY_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				length := 0
+				for i, arg := range args {
+					if n := len(arg.(*object.Array).Elements); i == 0 || n < length {
+						length = n
+					}
+				}
+
+				elements := make([]object.Object, length)
+				for i := range elements {
+					group := make([]object.Object, len(args))
+					for j, arg := range args {
+						group[j] = arg.(*object.Array).Elements[i]
+					}
+					elements[i] = allocate(runtime, &object.Array{Elements: group})
+					if isError(elements[i]) {
+						return elements[i]
+					}
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:     "range",
+			Doc:      "Returns an array of integers from start (0 by default) up to stop, excluded, by step (1 by default).",
+			Params:   []object.ObjectType{object.INTEGER_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) == 0 || len(args) > 3 {
+					return newError("`range` accepts 1 to 3 arguments, got %d", len(args))
+				}
+
+				bounds := make([]int64, len(args))
+				for i, arg := range args {
+					bounds[i] = arg.(*object.Integer).Value
+				}
+
+				start, stop, step := int64(0), bounds[0], int64(1)
+				if len(bounds) > 1 {
+					start, stop = bounds[0], bounds[1]
+				}
+				if len(bounds) > 2 {
+					step = bounds[2]
+				}
+
+				if step == 0 {
+					return newError("`range` step cannot be zero")
+				}
+
+				count := uint64(0)
+				if step > 0 && stop > start {
+					count = (distance(start, stop)-1)/uint64(step) + 1
+				} else if step < 0 && start > stop {
+					count = (distance(stop, start)-1)/uint64(-step) + 1
+				}
+
+				if count > math.MaxInt32 {
+					return newError("range is too large")
+				}
+
+				if err := runtime.AllocateArray(int64(count)); err != nil {
+					return err
+				}
+
+				elements := make([]object.Object, count)
+				for i := range elements {
+					elements[i] = &object.Integer{Value: start + int64(i)*step}
+				}
+
+				return &object.Array{Elements: elements}
+			},
+		},
+		{
+			Name:   "enumerate",
+			Doc:    "Returns an array of [index, element] pairs.",
+			Params: []object.ObjectType{object.ARRAY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				arr := args[0].(*object.Array)
+
+				elements := make([]object.Object, len(arr.Elements))
+				for i, element := range arr.Elements {
+					pair := &object.Array{Elements: []object.Object{&object.Integer{Value: int64(i)}, element}}
+					elements[i] = allocate(runtime, pair)
+					if isError(elements[i]) {
+						return elements[i]
+					}
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:     "sort",
+			Doc:      "Returns a new sorted array, the optional comparator returns a negative integer, zero or a positive integer, or true if the first argument goes first.",
+			Params:   []object.ObjectType{object.ARRAY_OBJ, object.ANY_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 2 {
+					return newError("`sort` accepts at most 2 arguments, got %d", len(args))
+				}
+
+				arr := args[0].(*object.Array)
+				elements := make([]object.Object, len(arr.Elements))
+				copy(elements, arr.Elements)
+
+				var err object.Object
+				sort.SliceStable(elements, func(i, j int) bool {
+					if err != nil {
+						return false
+					}
+
+					var less bool
+					if len(args) == 2 {
+						less, err = callComparator(runtime, args[1], elements[i], elements[j])
+					} else {
+						less, err = lessThan(elements[i], elements[j])
+					}
+					return less
+				})
+
+				if err != nil {
+					return err
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:   "reverse",
+			Doc:    "Returns a new array or string in reverse order.",
+			Params: []object.ObjectType{object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				switch arg := args[0].(type) {
+
+				case *object.Array:
+					length := len(arg.Elements)
+					elements := make([]object.Object, length)
+					for i, element := range arg.Elements {
+						elements[length-1-i] = element
+					}
+					return allocate(runtime, &object.Array{Elements: elements})
+
+				case *object.String:
+					runes := []rune(arg.Value)
+					for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+						runes[i], runes[j] = runes[j], runes[i]
+					}
+					return allocate(runtime, &object.String{Value: string(runes)})
+
+				default:
+					return newError("argument to `reverse` not supported, got %s", arg.Type())
+				}
+			},
+		},
+		{
+			Name:   "unique",
+			Doc:    "Returns a new array without repeated elements, the first occurrence is kept.",
+			Params: []object.ObjectType{object.ARRAY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				seen := &object.Hash{}
+				elements := []object.Object{}
+
+			outer:
+				for _, element := range args[0].(*object.Array).Elements {
+					if object.CanHash(element) {
+						if _, ok := seen.Get(element.(object.Hashable)); ok {
+							continue
+						}
+						seen.Set(element.(object.Hashable), TRUE)
+					} else {
+						for _, kept := range elements {
+							if object.Equal(kept, element) {
+								continue outer
+							}
+						}
+					}
+
+					elements = append(elements, element)
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:   "keys",
+			Doc:    "Returns keys of a hash in insertion order.",
+			Params: []object.ObjectType{object.HASH_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				pairs := args[0].(*object.Hash).Pairs()
+
+				elements := make([]object.Object, len(pairs))
+				for i, pair := range pairs {
+					elements[i] = pair.Key
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:   "values",
+			Doc:    "Returns values of a hash in insertion order of keys.",
+			Params: []object.ObjectType{object.HASH_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				pairs := args[0].(*object.Hash).Pairs()
+
+				elements := make([]object.Object, len(pairs))
+				for i, pair := range pairs {
+					elements[i] = pair.Value
+				}
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:   "contains",
+			Doc:    "Reports whether an array has the element, a hash has the key or a string has the substring.",
+			Params: []object.ObjectType{object.ANY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return evalInfixExpression(runtime, "in", args[1], args[0])
+			},
+		},
+	}
+}
+
+// callComparator calls a sort comparator, it may return an integer or a boolean
+func callComparator(runtime *object.Runtime, fn, a, b object.Object) (bool, object.Object) {
+	result := applyFunction(runtime, fn, []object.Object{a, b})
+
+	switch result := result.(type) {
+	case *object.Error:
+		return false, result
+	case *object.Integer:
+		return result.Value < 0, nil
+	case *object.Boolean:
+		return result.Value, nil
+	default:
+		return false, newError("comparator should return INTEGER or BOOLEAN, got %s", result.Type())
+	}
+}
+
+// lessThan is the default ordering of sort, only integers and strings can be
+// compared, each with the same type only
+func lessThan(a, b object.Object) (bool, object.Object) {
+	switch {
+	case a.Type() == object.INTEGER_OBJ && b.Type() == object.INTEGER_OBJ:
+		return a.(*object.Integer).Value < b.(*object.Integer).Value, nil
+	case a.Type() == object.STRING_OBJ && b.Type() == object.STRING_OBJ:
+		return a.(*object.String).Value < b.(*object.String).Value, nil
+	default:
+		return false, newError("cannot compare %s and %s", a.Type(), b.Type())
+	}
+}
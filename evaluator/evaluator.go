@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/lancelote/writing-an-interpreter-in-go/ast"
 	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"math"
+	"strings"
 )
 
 var (
@@ -13,6 +15,10 @@ var (
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if err := env.Runtime().Step(); err != nil {
+		return err
+	}
+
 	switch node := node.(type) {
 
 	case *ast.Program:
@@ -36,6 +42,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return nativeBoolToBooleanObject(node.Value)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -46,7 +56,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(env.Runtime(), node.Operator, left, right)
 
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
@@ -63,12 +73,20 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
+		if node.Exported && env.Enclosed() {
+			return newError("export is only allowed at the top level")
+		}
+
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		env.Set(node.Name.Value, val)
 
+		if node.Exported {
+			env.Export(node.Name.Value)
+		}
+
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
@@ -91,17 +109,20 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return applyFunction(env.Runtime(), function, args)
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Array{Elements: elements}
+		return allocate(env.Runtime(), &object.Array{Elements: elements})
 
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return allocate(env.Runtime(), &object.String{Value: node.Value})
+
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
@@ -114,11 +135,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 
-		return evalIndexExpression(left, index)
+		return evalIndexExpression(env.Runtime(), left, index)
+
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 
+	case *ast.SpreadExpression:
+		return newError("spread is only allowed in array literals, hash literals and call arguments")
+
+	case *ast.ImportExpression:
+		return evalImportExpression(node, env)
+
+	case *ast.MemberExpression:
+		left := Eval(node.Object, env)
+		if isError(left) {
+			return left
+		}
+
+		return evalMemberExpression(left, node.Member.Value)
+
 	}
 
 	return nil
@@ -143,16 +181,22 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
-	switch v := right.(type) {
-	case *object.Boolean:
-		return nativeBoolToBooleanObject(!v.Value)
-	case *object.Integer:
-		return nativeBoolToBooleanObject(v.Value == 0)
-	case *object.Null:
-		return TRUE
-	default:
-		return FALSE
+	return nativeBoolToBooleanObject(!isTruthy(right))
+}
+
+// evalLogicalExpression short-circuits and returns the operand deciding the
+// result, e.g. `name || "anonymous"`.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
 	}
+
+	if isTruthy(left) == (node.Operator == "||") {
+		return left
+	}
+
+	return Eval(node.Right, env)
 }
 
 func evalMinusPrefixExpression(right object.Object) object.Object {
@@ -164,16 +208,26 @@ func evalMinusPrefixExpression(right object.Object) object.Object {
 	return &object.Integer{Value: -value}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func evalInfixExpression(runtime *object.Runtime, operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
+		return evalStringInfixExpression(runtime, operator, left, right)
+	case operator == "*" && left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
+		return evalStringRepetition(runtime, left, right)
+	case operator == "*" && left.Type() == object.INTEGER_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringRepetition(runtime, right, left)
+	case operator == "+" && left.Type() == object.ARRAY_OBJ && right.Type() == object.ARRAY_OBJ:
+		return evalArrayConcatenation(runtime, left, right)
+	case operator == "+" && left.Type() == object.HASH_OBJ && right.Type() == object.HASH_OBJ:
+		return evalHashMerge(runtime, left, right)
+	case operator == "in":
+		return evalInExpression(left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -193,11 +247,19 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		// Go panics on integer division by zero
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -207,18 +269,101 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+func evalStringInfixExpression(runtime *object.Runtime, operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
 	switch operator {
 	case "+":
-		return &object.String{Value: leftVal + rightVal}
+		return allocate(runtime, &object.String{Value: leftVal + rightVal})
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "in":
+		return nativeBoolToBooleanObject(strings.Contains(rightVal, leftVal))
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+func evalStringRepetition(runtime *object.Runtime, str, count object.Object) object.Object {
+	value := str.(*object.String).Value
+	n := count.(*object.Integer).Value
+
+	if n < 0 {
+		return newError("negative repeat count: %d", n)
+	}
+
+	if n > 0 && int64(len(value)) > math.MaxInt32/n {
+		return newError("repeated string is too long")
+	}
+
+	if err := runtime.AllocateBytes(int64(len(value)) * n); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.Repeat(value, int(n))}
+}
+
+func evalArrayConcatenation(runtime *object.Runtime, left, right object.Object) object.Object {
+	leftElements := left.(*object.Array).Elements
+	rightElements := right.(*object.Array).Elements
+
+	elements := make([]object.Object, 0, len(leftElements)+len(rightElements))
+	elements = append(elements, leftElements...)
+	elements = append(elements, rightElements...)
+
+	return allocate(runtime, &object.Array{Elements: elements})
+}
+
+// evalHashMerge returns a new hash with pairs of both hashes, values of the
+// right one win, order of keys is kept
+func evalHashMerge(runtime *object.Runtime, left, right object.Object) object.Object {
+	merged := &object.Hash{}
+
+	for _, hash := range []object.Object{left, right} {
+		for _, pair := range hash.(*object.Hash).Pairs() {
+			merged.Set(pair.Key.(object.Hashable), pair.Value)
+		}
+	}
+
+	return allocate(runtime, merged)
+}
+
+// evalInExpression checks membership: substrings of strings, elements of
+// arrays and keys of hashes
+func evalInExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+
+	case *object.Array:
+		for _, element := range right.Elements {
+			if object.Equal(left, element) {
+				return TRUE
+			}
+		}
+		return FALSE
+
+	case *object.Hash:
+		if !object.CanHash(left) {
+			return FALSE
+		}
+		_, ok := right.Get(left.(object.Hashable))
+		return nativeBoolToBooleanObject(ok)
+
+	default:
+		return newError("unknown operator: %s in %s", left.Type(), right.Type())
+	}
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
 	if isError(condition) {
@@ -268,32 +413,66 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// evalInterpolatedString joins text with interpolated values, strings are
+// inserted as is and other values are inspected
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for _, part := range node.Parts {
+		evaluated := Eval(part, env)
+		if isError(evaluated) {
+			return evaluated
+		}
+		out.WriteString(evaluated.Inspect())
+	}
+
+	return allocate(env.Runtime(), &object.String{Value: out.String()})
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{}
+
+	for _, pair := range node.Pairs {
+		if spread, ok := pair.Key.(*ast.SpreadExpression); ok {
+			value := Eval(spread.Value, env)
+			if isError(value) {
+				return value
+			}
+
+			other, ok := value.(*object.Hash)
+			if !ok {
+				return newError("spread in hash literal expects HASH, got %s", value.Type())
+			}
+
+			for _, pair := range other.Pairs() {
+				hash.Set(pair.Key.(object.Hashable), pair.Value)
+			}
+			continue
+		}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+		key := Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
+		if !object.CanHash(key) {
 			return newError("unhashable: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(key.(object.Hashable), value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return allocate(env.Runtime(), hash)
 }
 
+// isTruthy decides truthiness for conditions and logical operators: null,
+// false, 0 and empty strings, arrays and hashes are falsy, everything else
+// is truthy.
 func isTruthy(obj object.Object) bool {
 	switch v := obj.(type) {
 	case *object.Null:
@@ -302,11 +481,26 @@ func isTruthy(obj object.Object) bool {
 		return v.Value
 	case *object.Integer:
 		return v.Value != 0
+	case *object.String:
+		return v.Value != ""
+	case *object.Array:
+		return len(v.Elements) != 0
+	case *object.Hash:
+		return v.Len() != 0
 	default:
-		return false
+		return true
 	}
 }
 
+// allocate accounts for the newly created object in the runtime, returns
+// either the object itself or an error if the allocation limit is exceeded.
+func allocate(runtime *object.Runtime, obj object.Object) object.Object {
+	if err := runtime.Allocate(obj); err != nil {
+		return err
+	}
+	return obj
+}
+
 func newError(format string, a ...any) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
@@ -319,37 +513,68 @@ func isError(obj object.Object) bool {
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
+	if val, ok := Lookup(node.Value, env); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
-		return builtin
+	return newError("identifier not found: %s", node.Value)
+}
+
+// Lookup resolves the name the same way identifiers are resolved: bindings
+// first, then builtins registered in the runtime and standard builtins.
+func Lookup(name string, env *object.Environment) (object.Object, bool) {
+	if val, ok := env.Get(name); ok {
+		return val, true
+	}
+
+	if builtin, ok := env.Runtime().Builtin(name); ok {
+		return builtin, true
 	}
 
-	return newError("identifier not found: %s", node.Value)
+	if builtin, ok := builtins[name]; ok {
+		return builtin, true
+	}
 
+	return nil, false
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range exps {
+		spread, isSpread := e.(*ast.SpreadExpression)
+		if isSpread {
+			e = spread.Value
+		}
+
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
-		result = append(result, evaluated)
+
+		if !isSpread {
+			result = append(result, evaluated)
+			continue
+		}
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			return []object.Object{newError("spread expects ARRAY, got %s", evaluated.Type())}
+		}
+		result = append(result, array.Elements...)
 	}
 
 	return result
 }
 
-func evalIndexExpression(left, index object.Object) object.Object {
+func evalIndexExpression(runtime *object.Runtime, left, index object.Object) object.Object {
 	switch {
 
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-		return evalArrayIndexExpression(left, index)
+		return evalArrayIndexExpression(runtime, left, index)
+
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(runtime, left, index)
 
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
@@ -359,44 +584,208 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	}
 }
 
-func evalArrayIndexExpression(array, index object.Object) object.Object {
-	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
+func evalArrayIndexExpression(runtime *object.Runtime, array, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+
+	idx, err := resolveIndex(runtime, index, len(elements))
+	if err != nil {
+		return err
+	}
+
+	if idx < 0 {
+		return NULL
+	}
+
+	return elements[idx]
+}
 
-	if idx < 0 || idx > max {
+// evalStringIndexExpression indexes runes of the string, not bytes
+func evalStringIndexExpression(runtime *object.Runtime, str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+
+	idx, err := resolveIndex(runtime, index, len(runes))
+	if err != nil {
+		return err
+	}
+
+	if idx < 0 {
 		return NULL
 	}
 
-	return arrayObject.Elements[idx]
+	return &object.String{Value: string(runes[idx])}
+}
+
+// resolveIndex turns a possibly negative index into a position in a sequence
+// of the given length, the position is -1 if the index is out of range and
+// runtime is not strict.
+func resolveIndex(runtime *object.Runtime, index object.Object, length int) (int, *object.Error) {
+	idx := index.(*object.Integer).Value
+	position := idx
+	if position < 0 {
+		position += int64(length)
+	}
+
+	if position < 0 || position >= int64(length) {
+		if runtime.StrictIndex {
+			return -1, newError("index out of range: %d, length %d", idx, length)
+		}
+		return -1, nil
+	}
+
+	return int(position), nil
+}
+
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var bounds []object.Object
+	for _, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+		if bound == nil {
+			bounds = append(bounds, nil)
+			continue
+		}
+
+		evaluated := Eval(bound, env)
+		if isError(evaluated) {
+			return evaluated
+		}
+
+		if evaluated.Type() != object.INTEGER_OBJ {
+			return newError("slice index should be INTEGER, got %s", evaluated.Type())
+		}
+		bounds = append(bounds, evaluated)
+	}
+
+	switch left := left.(type) {
+
+	case *object.String:
+		runes := []rune(left.Value)
+		positions, err := slicePositions(len(runes), bounds[0], bounds[1], bounds[2])
+		if err != nil {
+			return err
+		}
+
+		sliced := make([]rune, len(positions))
+		for i, position := range positions {
+			sliced[i] = runes[position]
+		}
+		return allocate(env.Runtime(), &object.String{Value: string(sliced)})
+
+	case *object.Array:
+		positions, err := slicePositions(len(left.Elements), bounds[0], bounds[1], bounds[2])
+		if err != nil {
+			return err
+		}
+
+		sliced := make([]object.Object, len(positions))
+		for i, position := range positions {
+			sliced[i] = left.Elements[position]
+		}
+		return allocate(env.Runtime(), &object.Array{Elements: sliced})
+
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+// slicePositions resolves optional bounds and step Python-style into the
+// positions of selected elements: negative bounds count from the end, out of
+// range bounds are clamped and a negative step walks backwards.
+func slicePositions(length int, start, end, step object.Object) ([]int, *object.Error) {
+	stride := int64(1)
+	if step != nil {
+		stride = step.(*object.Integer).Value
+	}
+
+	if stride == 0 {
+		return nil, newError("slice step cannot be zero")
+	}
+
+	// any longer step selects a single element, clamping avoids overflows
+	stride = min(max(stride, -int64(length)-1), int64(length)+1)
+
+	// a backward slice may stop before the first element
+	lower, upper := int64(0), int64(length)
+	if stride < 0 {
+		lower, upper = -1, int64(length)-1
+	}
+
+	resolve := func(bound object.Object, fallback int64) int64 {
+		if bound == nil {
+			return fallback
+		}
+
+		idx := bound.(*object.Integer).Value
+		if idx < 0 {
+			idx += int64(length)
+		}
+		return min(max(idx, lower), upper)
+	}
+
+	var positions []int
+	if stride > 0 {
+		for i := resolve(start, lower); i < resolve(end, upper); i += stride {
+			positions = append(positions, int(i))
+		}
+	} else {
+		for i := resolve(start, upper); i > resolve(end, lower); i += stride {
+			positions = append(positions, int(i))
+		}
+	}
+
+	return positions, nil
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
-	if !ok {
+	if !object.CanHash(index) {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Get(index.(object.Hashable))
 	if !ok {
 		return NULL
 	}
 
-	return pair.Value
+	return value
+}
+
+// Apply calls a Monkey function or builtin with the given arguments.
+func Apply(runtime *object.Runtime, fn object.Object, args []object.Object) object.Object {
+	return applyFunction(runtime, fn, args)
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func applyFunction(runtime *object.Runtime, fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 
 	case *object.Function:
+		// missing arguments would crash extendFunctionEnv, extra ones are
+		// ignored
+		if len(args) < len(fn.Parameters) {
+			return newError("wrong number of arguments: want %d, got %d", len(fn.Parameters), len(args))
+		}
+
+		if err := runtime.Enter(); err != nil {
+			return err
+		}
+		defer runtime.Leave()
+
 		extendedEnv := extendFunctionEnv(fn, args)
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if msg := fn.CheckArgs(args); msg != "" {
+			return newError("%s", msg)
+		}
+		if result := fn.Fn(runtime, args...); result != nil {
+			return result
+		}
+		return NULL
 
 	default:
 		return newError("not a function: %s", fn.Type())
@@ -413,10 +802,15 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 	return env
 }
 
+// unwrapReturnValue returns the result of a function call, functions with an
+// empty body or ending with a let statement return null
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
 	}
 
+	if obj == nil {
+		return NULL
+	}
 	return obj
 }
@@ -1,10 +1,18 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"github.com/lancelote/writing-an-interpreter-in-go/lexer"
 	"github.com/lancelote/writing-an-interpreter-in-go/object"
 	"github.com/lancelote/writing-an-interpreter-in-go/parser"
+	"io/fs"
+	"math/rand/v2"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -79,6 +87,15 @@ func TestBangOperator(t *testing.T) {
 		{"!!false", false},
 		{"!!5", true},
 		{"!0", true},
+		{`!""`, true},
+		{`!"a"`, false},
+		{"![]", true},
+		{"![0]", false},
+		{"!{}", true},
+		{`!{"a": 1}`, false},
+		{"!fn() {}", false},
+		{"!len", false},
+		{"!if (false) { 1 }", true},
 	}
 
 	for _, tt := range tests {
@@ -100,6 +117,14 @@ func TestIfElseExpression(t *testing.T) {
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
 		{"if (0) { 1 } else { 2 }", 2},
+		{`if ("") { 1 } else { 2 }`, 2},
+		{`if ("a") { 1 } else { 2 }`, 1},
+		{"if ([]) { 1 } else { 2 }", 2},
+		{"if ([0]) { 1 } else { 2 }", 1},
+		{"if ({}) { 1 } else { 2 }", 2},
+		{"if ({0: 0}) { 1 } else { 2 }", 1},
+		{"if (fn() {}) { 1 } else { 2 }", 1},
+		{"let f = fn() {}; if (f()) { 1 } else { 2 }", 2},
 	}
 
 	for _, tt := range tests {
@@ -113,6 +138,45 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && 2", 2},
+		{"0 && 2", 0},
+		{"0 || 2", 2},
+		{"1 || 2", 1},
+		{`"" || "default"`, "default"},
+		{`"name" || "default"`, "name"},
+		{"[] || [] && 1", "[]"},
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"true && undefined", "identifier not found: undefined"},
+		{"undefined || true", "identifier not found: undefined"},
+		{"!(1 && 0) == (!1 || !0)", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated.Inspect() != expected && evaluated.Inspect() != "ERROR: "+expected {
+				t.Errorf("want %q, got %q", expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -195,6 +259,14 @@ if (10 > 1) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"fn(x, y) { x + y }(1)",
+			"wrong number of arguments: want 2, got 1",
+		},
+		{
+			"let zero = 0; 1 / zero",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -262,6 +334,7 @@ func TestFunctionApplication(t *testing.T) {
 		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
 		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
 		{"fn(x) { x; }(5)", 5},
+		{"fn(x) { x; }(5, 6)", 5},
 	}
 
 	for _, tt := range tests {
@@ -309,6 +382,71 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringOperations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`"a" < "b"`, true},
+		{`"b" <= "a"`, false},
+		{`"abc" >= "abc"`, true},
+		{`"abc" == "abc"`, true},
+		{`"abc" != "abd"`, true},
+		{`"héllo"[1]`, "é"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, "c"},
+		{`"abc"[-4]`, nil},
+		{`"héllo"[1:3]`, "él"},
+		{`"hello"[:2]`, "he"},
+		{`"hello"[3:]`, "lo"},
+		{`"hello"[-3:-1]`, "ll"},
+		{`"hello"[4:1]`, ""},
+		{`"hello"[0:100]`, "hello"},
+		{`"hello"["a":]`, "slice index should be INTEGER, got STRING"},
+		{`{"a": 1}[0:1]`, "slice operator not supported: HASH"},
+		{`"ab" * 3`, "ababab"},
+		{`2 * "ab"`, "abab"},
+		{`"ab" * 0`, ""},
+		{`"ab" * -1`, "negative repeat count: -1"},
+		{`"ell" in "hello"`, true},
+		{`"x" in "hello"`, false},
+		{`2 in [1, 2, 3]`, true},
+		{`[1] in [[1], [2]]`, true},
+		{`4 in [1, 2, 3]`, false},
+		{`"a" in {"a": 1}`, true},
+		{`"b" in {"a": 1}`, false},
+		{`fn(x) { x } in {"a": 1}`, false},
+		{`1 in 2`, "unknown operator: INTEGER in INTEGER"},
+		{`len("héllo")`, 5},
+		{`let s = "héllo"; s[len(s) - 1]`, "o"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if evaluated.Inspect() != expected && evaluated.Inspect() != "ERROR: "+expected {
+				t.Errorf("%s: want %q, got %q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestStringRepetitionAllocation(t *testing.T) {
+	program := parser.New(lexer.New(`"abc" * 1000000`)).ParseProgram()
+	env := object.NewEnvironment()
+	env.Runtime().MaxAlloc = 1000
+
+	testAbortError(t, Eval(program, env), object.ErrAllocLimit)
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -321,6 +459,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("one", "two")`, "wrong number of arguments, want 1, got 2"},
 		{`len([1, 2, 3])`, 3},
 		{`len([])`, 0},
+		{`args()`, []int{}},
+		{`args(1)`, "`args` accepts 0 arguments, got 1"},
 		{`first([1, 2])`, 1},
 		{`first("")`, "argument to `first` should be ARRAY, got STRING"},
 		{`first([1, 2], [3, 4])`, "`first` accepts 1 argument, got 2"},
@@ -340,6 +480,10 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`push([1, 2], 3)`, []int{1, 2, 3}},
 		{`push([], 1)`, []int{1}},
 		{`push(1, 2)`, "first argument to `push` should be ARRAY, got INTEGER"},
+		{`exit(1, 2)`, "`exit` accepts at most 1 argument, got 2"},
+		{`exit("1")`, "argument to `exit` should be INTEGER, got STRING"},
+		{`help(1)`, "argument 1 to `help` should be BUILTIN, got INTEGER"},
+		{`help()`, "`help` accepts 1 argument, got 0"},
 		{
 			`
 let map = fn(arr, f) {
@@ -411,6 +555,674 @@ sum([1, 2, 3, 4, 5]);
 	}
 }
 
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], fn(x) { x * 2 })", "[2, 4, 6]"},
+		{"[1, 2].map(fn(x) { x + 1 })", "[2, 3]"},
+		{"filter([1, 2, 3, 4], fn(x) { x > 2 })", "[3, 4]"},
+		{"[0, 1, 2].filter(fn(x) { x })", "[1, 2]"},
+		{"reduce([1, 2, 3], fn(acc, x) { acc + x })", "6"},
+		{"reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)", "16"},
+		{"reduce([], fn(acc, x) { acc + x }, 0)", "0"},
+		{"reduce([], fn(acc, x) { acc + x })", "ERROR: `reduce` of empty array with no initial value"},
+		{"each([1, 2], fn(x) { x })", "null"},
+		{"each([1, 2], fn(x) { undefined })", "ERROR: identifier not found: undefined"},
+		{"any([1, 2, 3], fn(x) { x > 2 })", "true"},
+		{"any([], fn(x) { true })", "false"},
+		{"all([1, 2, 3], fn(x) { x > 0 })", "true"},
+		{"all([1, 2, 3], fn(x) { x > 1 })", "false"},
+		{"find([1, 2, 3], fn(x) { x > 1 })", "2"},
+		{"find([1, 2, 3], fn(x) { x > 5 })", "null"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{"zip()", "[]"},
+		{"range(3)", "[0, 1, 2]"},
+		{"range(2, 5)", "[2, 3, 4]"},
+		{"range(0, 10, 3)", "[0, 3, 6, 9]"},
+		{"range(3, 0, -1)", "[3, 2, 1]"},
+		{"range(3, 0)", "[]"},
+		{"range(0, 9223372036854775807, 4611686018427387904)", "[0, 4611686018427387904]"},
+		{"range(1, 2, 0)", "ERROR: `range` step cannot be zero"},
+		{"range(0, 9223372036854775807)", "ERROR: range is too large"},
+		{"range()", "ERROR: `range` accepts 1 to 3 arguments, got 0"},
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{"sort([3, 1, 2])", "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{"sort([3, 1, 2], fn(a, b) { b - a })", "[3, 2, 1]"},
+		{"sort([3, 1, 2], fn(a, b) { a > b })", "[3, 2, 1]"},
+		{`sort([[2, "b"], [1, "a"], [2, "a"]], fn(a, b) { a[0] < b[0] })`, "[[1, a], [2, b], [2, a]]"},
+		{"let a = [2, 1]; sort(a); a", "[2, 1]"},
+		{`sort([1, "a"])`, "ERROR: cannot compare STRING and INTEGER"},
+		{`sort([1, 2], fn(a, b) { "a" })`, "ERROR: comparator should return INTEGER or BOOLEAN, got STRING"},
+		{"sort([2, 1], fn(a, b) {})", "ERROR: comparator should return INTEGER or BOOLEAN, got NULL"},
+		{"map([1, 2], fn(x) {})", "[null, null]"},
+		{"map([1], fn(x, y) { x })", "ERROR: wrong number of arguments: want 2, got 1"},
+		{"filter([1, 2], fn(x) {})", "[]"},
+		{"reduce([1, 2], fn(acc, x) {})", "null"},
+		{"find([1, 2], fn(x) {})", "null"},
+		{"reverse([1, 2, 3])", "[3, 2, 1]"},
+		{`"héllo".reverse()`, "olléh"},
+		{"reverse(1)", "ERROR: argument to `reverse` not supported, got INTEGER"},
+		{"unique([1, 2, 1, [3], [3], 2])", "[1, 2, [3]]"},
+		{"let f = fn() { 1 }; len(unique([f, f]))", "1"},
+		{`keys({"a": 1, "b": 2})`, "[a, b]"},
+		{`{"a": 1, "b": 2}.values()`, "[1, 2]"},
+		{"contains([1, 2], 2)", "true"},
+		{`contains({"a": 1}, "b")`, "false"},
+		{`"hello".contains("ell")`, "true"},
+		{"map([1], 1)", "ERROR: not a function: INTEGER"},
+		{"map([1, 2], fn(x) { x + undefined })", "ERROR: identifier not found: undefined"},
+		{"map(1, fn(x) { x })", "ERROR: argument 1 to `map` should be ARRAY, got INTEGER"},
+		{"map([1, 2], len)", "ERROR: argument to `len` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`split("a,b,,c", ",")`, `[a, b, , c]`},
+		{"split(\"  a b\tc \")", `[a, b, c]`},
+		{`"a-b".split("-")`, `[a, b]`},
+		{`join(["a", "b"], ", ")`, "a, b"},
+		{`join([1, true, "x"])`, "1truex"},
+		{`[1, 2].join("+")`, "1+2"},
+		{`trim("  a b  ")`, "a b"},
+		{`trim("xxaxx", "x")`, "a"},
+		{`upper("abc")`, "ABC"},
+		{`"ABC".lower()`, "abc"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`contains("hello", "ell")`, "true"},
+		{`starts_with("hello", "he")`, "true"},
+		{`"hello".ends_with("he")`, "false"},
+		{`index_of("héllo", "l")`, "2"},
+		{`index_of("hello", "x")`, "-1"},
+		{`index_of([1, 2, 3], 3)`, "2"},
+		{`[1, 2].index_of(5)`, "-1"},
+		{`index_of("hello", 1)`, "ERROR: argument 2 to `index_of` should be STRING, got INTEGER"},
+		{`chars("hé")`, "[h, é]"},
+		{`repeat("ab", 2)`, "abab"},
+		{`"ab".repeat(-1)`, "ERROR: negative repeat count: -1"},
+		{`pad("7", 3, "0")`, "007"},
+		{`pad("ab", -4)`, "ab  "},
+		{`"héllo".pad(3)`, "héllo"},
+		{`pad("a", 3, "ab")`, `ERROR: ` + "`pad`" + ` fill should be a single rune, got "ab"`},
+		{`pad("a", 9223372036854775807)`, "ERROR: padded string is too long"},
+		{`str(12)`, "12"},
+		{`str([1, "a"])`, "[1, a]"},
+		{`str("a")`, "a"},
+		{`let f = fn() {}; str(f())`, "null"},
+		{`int("42")`, "42"},
+		{`int(" -7 ")`, "-7"},
+		{`int(3)`, "3"},
+		{`int("4x")`, `ERROR: cannot parse "4x" as INTEGER`},
+		{`int(true)`, "ERROR: argument to `int` not supported, got BOOLEAN"},
+		{`upper(1)`, "ERROR: argument 1 to `upper` should be STRING, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`format("{} has {}", "Bob", 3)`, "Bob has 3"},
+		{`format("{1} {0} {1}", "a", "b")`, "b a b"},
+		{`format("{{}} {}", 1)`, "{} 1"},
+		{`let f = fn() {}; format("{}", f())`, "null"},
+		{`format("[{:5}]", 42)`, "[   42]"},
+		{`format("[{:5}]", "ab")`, "[ab   ]"},
+		{`format("[{:>5}]", "ab")`, "[   ab]"},
+		{`format("[{:<5}]", 42)`, "[42   ]"},
+		{`format("[{:^6}]", "ab")`, "[  ab  ]"},
+		{`format("[{:05}]", -42)`, "[-0042]"},
+		{`format("[{:.3}]", 7)`, "[007]"},
+		{`format("[{:6.3}]", -7)`, "[  -007]"},
+		{`format("[{:.2}]", "héllo")`, "[hé]"},
+		{`format("{}", [1, "a"])`, "[1, a]"},
+		{`format("{} {}", 1)`, "ERROR: format string refers to argument 1, got 1 arguments"},
+		{`format("{", 1)`, "ERROR: unclosed placeholder in format string"},
+		{`format("}", 1)`, "ERROR: single `}` in format string"},
+		{`format("{x}", 1)`, "ERROR: invalid placeholder in format string: {x}"},
+		{`format("{:x}", 1)`, `ERROR: invalid format spec: "x"`},
+		{`format("{:99999999999}", 1)`, `ERROR: invalid format spec: "99999999999"`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Bob"; "hi ${name}!"`, "hi Bob!"},
+		{`"${1 + 2} = ${3}"`, "3 = 3"},
+		{`let h = {"a": [1, 2]}; "${h["a"]} ${len(h)}"`, "[1, 2] 1"},
+		{`"outer ${"inner ${1}"}"`, "outer inner 1"},
+		{`"${true}${fn(x) { x }(5)}"`, "true5"},
+		{`"a ${undefined}"`, "ERROR: identifier not found: undefined"},
+		{`let x = 1; "#!${x}"`, "#!1"},
+		{`let f = fn() {}; "${f()}"`, "null"},
+		{`let f = fn() { let x = 1; }; "${f()}"`, "null"},
+		{`let n = 4; quote("n=${unquote(n)}")`, "QUOTE(n=${4})"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		doc      string // bound to `doc`, Monkey strings can't have quotes
+		input    string
+		expected string
+	}{
+		{"", `let null = if (false) { 1 }; json_encode({"b": [1, true, null], "a": "x<y"})`, `{"b":[1,true,null],"a":"x<y"}`},
+		{"", `json_encode([])`, `[]`},
+		{"", `json_encode({"a": [1, {}]}, true)`, "{\n  \"a\": [\n    1,\n    {}\n  ]\n}"},
+		{"", `json_encode({1: 2})`, "ERROR: cannot encode hash key of type INTEGER as JSON, keys should be STRING"},
+		{"", `json_encode([fn(x) { x }])`, "ERROR: cannot encode FUNCTION as JSON"},
+		{"tab\t\"quoted\"", `json_encode(doc)`, `"tab\t\"quoted\""`},
+		{`{"b": 1, "a": [true, null, "s"]}`, `json_decode(doc)`, "{b: 1, a: [true, null, s]}"},
+		{" 42 ", `json_decode(doc)`, "42"},
+		{`"\u00e9\n"`, `json_decode(doc) == "é" + json_decode(doc)[1]`, "true"},
+		{`{"a": 1, "a": 2}`, `json_decode(doc)`, "{a: 2}"},
+		{"[1, 2", `json_decode(doc)`, "ERROR: malformed JSON: unexpected end of JSON input"},
+		{"", `json_decode(doc)`, "ERROR: malformed JSON: unexpected EOF"},
+		{"[1] [2]", `json_decode(doc)`, "ERROR: malformed JSON: unexpected data after the value"},
+		{"{1: 2}", `json_decode(doc)`, "ERROR: malformed JSON: object member name must be a string"},
+		{"1.5", `json_decode(doc)`, "ERROR: cannot decode JSON number 1.5 as INTEGER"},
+		{strings.Repeat("[", 2000), `json_decode(doc)`, "ERROR: malformed JSON: nesting is deeper than 1000"},
+		{`{"a": [1, "b"], "c": {"d": null}}`, `json_decode(json_encode(json_decode(doc))) == json_decode(doc)`, "true"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Set("doc", &object.String{Value: tt.doc})
+
+		evaluated := Eval(program, env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"abs(-5)", "5"},
+		{"abs(5)", "5"},
+		{"abs(-9223372036854775807 - 1)", "ERROR: integer overflow"},
+		{"min(3, 1, 2)", "1"},
+		{"max([3, 1, 2])", "3"},
+		{`min("b", "a")`, "a"},
+		{"[4, 9].max()", "9"},
+		{"min([])", "ERROR: `min` of no values"},
+		{`max(1, "a")`, "ERROR: cannot compare INTEGER and STRING"},
+		{"pow(2, 10)", "1024"},
+		{"pow(-3, 3)", "-27"},
+		{"pow(5, 0)", "1"},
+		{"pow(2, 63)", "ERROR: integer overflow"},
+		{"pow(2, -1)", "ERROR: `pow` exponent should be non-negative, got -1"},
+		{"sqrt(16)", "4"},
+		{"sqrt(17)", "4"},
+		{"sqrt(9223372036854775807)", "3037000499"},
+		{"sqrt(-1)", "ERROR: `sqrt` of negative number -1"},
+		{"floor(7, 2)", "3"},
+		{"floor(-7, 2)", "-4"},
+		{"floor(7, -2)", "-4"},
+		{"floor(5)", "5"},
+		{"ceil(7, 2)", "4"},
+		{"ceil(-7, 2)", "-3"},
+		{"round(7, 2)", "4"},
+		{"round(-7, 2)", "-4"},
+		{"round(7, 3)", "2"},
+		{"round(8, 3)", "3"},
+		{"floor(1, 0)", "ERROR: division by zero"},
+		{"clamp(15, 0, 10)", "10"},
+		{"clamp(-5, 0, 10)", "0"},
+		{"clamp(5, 10, 0)", "ERROR: `clamp` low bound 10 is greater than high bound 0"},
+		{"gcd(12, -18)", "6"},
+		{"gcd(0, 0)", "0"},
+		{"choice([])", "ERROR: `choice` of empty array"},
+		{"rand_int(5, 5)", "ERROR: `rand_int` range is empty: from 5 to 5"},
+		{"let n = rand_int(3, 6); n >= 3 && n < 6", "true"},
+		{"sort(shuffle([3, 1, 2]))", "[1, 2, 3]"},
+		{"contains([1, 2], choice([1, 2]))", "true"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSeededRandom(t *testing.T) {
+	input := "[rand_int(1000000), shuffle(range(10)), choice(range(100))]"
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	run := func(seed uint64) string {
+		env := object.NewEnvironment()
+		env.Runtime().Rand = rand.New(rand.NewPCG(seed, seed))
+		return Eval(program, env).Inspect()
+	}
+
+	if run(1) != run(1) {
+		t.Errorf("want the same results for the same seed")
+	}
+
+	if run(1) == run(2) {
+		t.Errorf("want different results for different seeds")
+	}
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regex("a+b")`, "/a+b/"},
+		{`regex("(")`, "ERROR: invalid regex: error parsing regexp: missing closing ): `(`"},
+		{`regex("\d+") == regex("\d+")`, "true"},
+		{`regex("\d+").match("abc 42")`, "true"},
+		{`regex("^\d+$").match("abc 42")`, "false"},
+		{`regex("\d+").find_all("1 22 333")`, "[1, 22, 333]"},
+		{`regex("\d+").find_all("1 22 333", 2)`, "[1, 22]"},
+		{`regex("x").find_all("abc")`, "[]"},
+		{`regex("(\w+)@(\w+)?").captures("mail: bob@ rest")`, "[bob@, bob, null]"},
+		{`regex("(\d+)").captures("none")`, "null"},
+		{`regex("(?P<y>\d{4})-(?P<m>\d{2})").named_captures("on 2024-05")`, "{y: 2024, m: 05}"},
+		{`regex("(\w+)=(\w+)").replace("a=1, b=2", "$2=$1")`, "1=a, 2=b"},
+		{`regex("[aeiou]").replace("hello", fn(m) { upper(m) })`, "hEllO"},
+		{`regex("\d").replace("a1b2", fn(m) { str(int(m) * 2) })`, "a2b4"},
+		{`regex("\d").replace("a1b2", fn(m) { int(m) * 2 })`, "ERROR: `replace` function should return STRING, got INTEGER"},
+		{`regex("\d").replace("a1", fn(m) {})`, "ERROR: `replace` function should return STRING, got NULL"},
+		{`regex("\d").replace("a1", fn(m) { undefined })`, "ERROR: identifier not found: undefined"},
+		{`regex("\s*,\s*").split("a , b,c")`, "[a, b, c]"},
+		{`regex(",").split("a,b,c", 2)`, "[a, b,c]"},
+		{`regex("a").match(1)`, "ERROR: argument 1 to `match` should be STRING, got INTEGER"},
+		{`regex("a").unknown`, "ERROR: unknown method of REGEX: unknown"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	frozen := time.Date(2024, time.March, 10, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"now()", "2024-03-10T12:30:00Z"},
+		{"now() == now()", "true"},
+		{`now().format("2006-01-02 15:04")`, "2024-03-10 12:30"},
+		{`now().add(90).format()`, "2024-03-10T12:31:30Z"},
+		{`now().add("-1h30m")`, "2024-03-10T11:00:00Z"},
+		{`now().add("soon")`, `ERROR: invalid duration: time: invalid duration "soon"`},
+		{`now().add(true)`, "ERROR: argument 2 to `add` should be INTEGER or STRING, got BOOLEAN"},
+		{`now().add(9223372036854775807)`, "ERROR: duration is too long: 9223372036854775807 seconds"},
+		{`now().diff(parse_time("2024-03-10T12:00:00Z"))`, "1800"},
+		{`parse_time("2024-03-10T12:00:00Z").diff(now())`, "-1800"},
+		{`parse_time("10.03.2024", "02.01.2006")`, "2024-03-10T00:00:00Z"},
+		{`parse_time("yesterday")`, `ERROR: cannot parse time: parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`},
+		{`now().in_zone("Asia/Tokyo")`, "2024-03-10T21:30:00+09:00"},
+		{`now().in_zone("Asia/Tokyo") == now()`, "true"},
+		{`now().in_zone("Mars/Olympus")`, `ERROR: unknown time zone "Mars/Olympus"`},
+		{"now().unix()", "1710073800"},
+		{"now(1)", "ERROR: `now` accepts 0 arguments, got 1"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Runtime().Clock = func() time.Time { return frozen }
+
+		evaluated := Eval(program, env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+// writableMapFS is a WriteFS keeping files in memory
+type writableMapFS struct {
+	fstest.MapFS
+}
+
+func (m writableMapFS) WriteFile(name string, data []byte) error {
+	m.MapFS[name] = &fstest.MapFile{Data: data}
+	return nil
+}
+
+func TestFiles(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`read_file("data/in.txt")`, "hello"},
+		{`read_file("/data/in.txt")`, "hello"},
+		{`read_file("./data/../data/in.txt")`, "hello"},
+		{`read_file("missing.txt")`, "ERROR: cannot read file: open missing.txt: file does not exist"},
+		{`read_file("../secret")`, `ERROR: invalid path "../secret"`},
+		{`read_file("data/../../secret")`, `ERROR: invalid path "data/../../secret"`},
+		{`write_file("out.txt", "result"); read_file("out.txt")`, "result"},
+		{`list_dir("data")`, "[in.txt, more.txt]"},
+		{`list_dir("/")`, "[data]"},
+		{`list_dir("nope")`, "ERROR: cannot list directory: open nope: file does not exist"},
+		{`exists("data/in.txt")`, "true"},
+		{`exists("data")`, "true"},
+		{`exists("data/none.txt")`, "false"},
+		{`path_join("a", "b/", "../c.txt")`, "a/c.txt"},
+		{`path_base("a/b/c.txt")`, "c.txt"},
+		{`path_dir("a/b/c.txt")`, "a/b"},
+		{`path_ext("a/b/c.tar.gz")`, ".gz"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Runtime().FS = writableMapFS{fstest.MapFS{
+			"data/in.txt":   {Data: []byte("hello")},
+			"data/more.txt": {Data: []byte("more")},
+		}}
+
+		evaluated := Eval(program, env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFilesRestricted(t *testing.T) {
+	tests := []struct {
+		fs       fs.FS
+		input    string
+		expected string
+	}{
+		{nil, `read_file("a.txt")`, "ERROR: file system access is not allowed"},
+		{nil, `exists("a.txt")`, "ERROR: file system access is not allowed"},
+		{fstest.MapFS{}, `write_file("a.txt", "x")`, "ERROR: file system is read-only"},
+		{fstest.MapFS{"a.txt": {Data: []byte("abcdef")}}, `read_file("a.txt")`, "ERROR: evaluation aborted: allocation limit exceeded"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Runtime().FS = tt.fs
+		env.Runtime().MaxAlloc = 8 // enough for the path, not for the file
+
+		evaluated := Eval(program, env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	modules := fstest.MapFS{
+		"lib/math.mk":    {Data: []byte(`let secret = 1; export let add = fn(a, b) { a + b }; export let answer = add(40, 2);`)},
+		"lib/a.mk":       {Data: []byte(`import "b"`)},
+		"lib/b.mk":       {Data: []byte(`import "a"`)},
+		"lib/broken.mk":  {Data: []byte(`export let 1`)},
+		"lib/failing.mk": {Data: []byte(`1 + true`)},
+		"vendor/util.mk": {Data: []byte(`let math = import "math"; export let double = fn(x) { math.add(x, x) };`)},
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = import "math"; m.add(1, 2)`, "3"},
+		{`(import "math").answer`, "42"},
+		{`import "math.mk"`, "module lib/math.mk"},
+		{`import "math" == import "math"`, "true"},
+		{`(import "math").secret`, "ERROR: module lib/math.mk has no export secret"},
+		{`(import "util").double(21)`, "42"},
+		{`import "a"`, "ERROR: import cycle: lib/a.mk -> lib/b.mk -> lib/a.mk"},
+		{`import "nope"`, `ERROR: module "nope.mk" not found`},
+		{`import "../../etc/passwd"`, `ERROR: invalid module path "../../etc/passwd.mk"`},
+		{`import "broken"`, `ERROR: cannot parse module "lib/broken.mk": want token IDENT, got INT`},
+		{`import "failing"`, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{`let f = fn() { export let x = 1; }; f()`, "ERROR: export is only allowed at the top level"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Runtime().ModuleFS = modules
+		env.Runtime().ModulePath = []string{"lib", "vendor"}
+
+		evaluated := Eval(testParseProgram(tt.input), env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestImportOnce(t *testing.T) {
+	var stdout bytes.Buffer
+
+	runtime := object.NewRuntime()
+	runtime.Stdout = &stdout
+	runtime.ModuleFS = fstest.MapFS{"greet.mk": {Data: []byte(`puts("loaded"); export let name = "greet";`)}}
+
+	env := object.NewEnvironmentWithRuntime(runtime)
+	evaluated := Eval(testParseProgram(`import "greet"; (import "greet").name`), env)
+	if evaluated.Inspect() != "greet" {
+		t.Errorf("want exported name, got %q", evaluated.Inspect())
+	}
+
+	if stdout.String() != "loaded\n" {
+		t.Errorf("want module evaluated once, got output %q", stdout.String())
+	}
+}
+
+func TestImportNotAllowed(t *testing.T) {
+	evaluated := Eval(testParseProgram(`import "lib"`), object.NewEnvironment())
+	if evaluated.Inspect() != "ERROR: imports are not allowed" {
+		t.Errorf("want imports disallowed, got %q", evaluated.Inspect())
+	}
+}
+
+func TestRangeAllocation(t *testing.T) {
+	program := parser.New(lexer.New("range(1000000)")).ParseProgram()
+	env := object.NewEnvironment()
+	env.Runtime().MaxAlloc = 1000
+
+	testAbortError(t, Eval(program, env), object.ErrAllocLimit)
+}
+
+func TestPutsOutput(t *testing.T) {
+	var stdout bytes.Buffer
+
+	runtime := object.NewRuntime()
+	runtime.Stdout = &stdout
+
+	env := object.NewEnvironmentWithRuntime(runtime)
+	evaluated := Eval(testParseProgram(`puts("hello", 1); puts([1, 2])`), env)
+
+	testNullObject(t, evaluated)
+
+	expected := "hello\n1\n[1, 2]\n"
+	if stdout.String() != expected {
+		t.Errorf("want output %q, got %q", expected, stdout.String())
+	}
+}
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"exit()", 0},
+		{"exit(3)", 3},
+		{"let f = fn() { exit(2); 10 }; f(); 20", 2},
+	}
+
+	for _, tt := range tests {
+		var code int
+
+		runtime := object.NewRuntime()
+		runtime.OnExit = func(c int) { code = c }
+
+		env := object.NewEnvironmentWithRuntime(runtime)
+		evaluated := Eval(testParseProgram(tt.input), env)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("want error, got %T", evaluated)
+			continue
+		}
+
+		var exitErr *object.ExitError
+		if !errors.As(errObj.Cause, &exitErr) {
+			t.Errorf("want exit error, got %q", errObj.Message)
+			continue
+		}
+
+		if exitErr.Code != tt.expected {
+			t.Errorf("want exit code %d, got %d", tt.expected, exitErr.Code)
+		}
+
+		if code != tt.expected {
+			t.Errorf("want exit handler called with %d, got %d", tt.expected, code)
+		}
+	}
+}
+
+func TestHostObjects(t *testing.T) {
+	counter := &object.Host{
+		TypeName: "Counter",
+		Value:    new(int64),
+		Methods: map[string]*object.Builtin{
+			"add": {
+				Name:   "add",
+				Params: []object.ObjectType{object.HOST_OBJ, object.INTEGER_OBJ},
+				Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+					value := args[0].(*object.Host).Value.(*int64)
+					*value += args[1].(*object.Integer).Value
+					return args[0]
+				},
+			},
+			"value": {
+				Name:   "value",
+				Params: []object.ObjectType{object.HOST_OBJ},
+				Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+					return &object.Integer{Value: *args[0].(*object.Host).Value.(*int64)}
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"counter.add(2).add(3).value()", 5},
+		{"let add = counter.add; add(10); counter.value()", 15},
+		{"counter", "<Counter>"},
+		{"counter.add()", "`add` accepts 1 argument, got 0"},
+		{"counter.missing()", "unknown member of Counter: missing"},
+		{"[1].value()", "unknown method of ARRAY: value"},
+		{"counter == counter", true},
+	}
+
+	env := object.NewEnvironment()
+	env.Set("counter", counter)
+
+	for _, tt := range tests {
+		evaluated := Eval(testParseProgram(tt.input), env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated.Inspect() != expected && evaluated.Inspect() != "ERROR: "+expected {
+				t.Errorf("want %q, got %q", expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`{"name": "monkey"}.name`, "monkey"},
+		{`let h = {"name": {"first": "m"}}; h.name.first`, "m"},
+		{`{"name": "monkey"}.age`, nil},
+		{`{"len": 10}.len`, 10},
+		{`{"a": 1, "b": 2}.len()`, 2},
+		{`"abc".upper()`, "ABC"},
+		{`"aBc".lower().upper().len()`, 3},
+		{`let upper = "abc".upper; upper()`, "ABC"},
+		{`[1, 2, 3].map(fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`[1, 2, 3].map(fn(x) { x * 2 }).push(7).rest().last()`, 7},
+		{`[1, 2].map(len)`, "argument to `len` not supported, got INTEGER"},
+		{`[1, 2].map()`, "`map` accepts 1 argument, got 0"},
+		{`"abc".upper(1)`, "`upper` accepts 0 arguments, got 1"},
+		{`"abc".missing()`, "unknown method of STRING: missing"},
+		{`5.len()`, "unknown method of INTEGER: len"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int:
+			testArrayObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if evaluated.Inspect() != expected && evaluated.Inspect() != "ERROR: "+expected {
+				t.Errorf("want %q, got %q", expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -472,6 +1284,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -487,6 +1307,104 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestArraySliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][-10:10]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[1, 2, 3, 4][::2]", []int{1, 3}},
+		{"[1, 2, 3, 4][1::2]", []int{2, 4}},
+		{"[1, 2, 3, 4][::-1]", []int{4, 3, 2, 1}},
+		{"[1, 2, 3, 4][2::-1]", []int{3, 2, 1}},
+		{"[1, 2, 3, 4][:0:-2]", []int{4, 2}},
+		{"[1, 2, 3, 4][::9223372036854775807]", []int{1}},
+		{"[1, 2, 3, 4][::-9223372036854775807]", []int{4}},
+		{`"hello"[::-1]`, "olleh"},
+		{`"hello"[1:4:2]`, "el"},
+		{"[1, 2][::0]", "slice step cannot be zero"},
+		{`[1, 2][:"a"]`, "slice index should be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case []int:
+			testArrayObject(t, evaluated, expected)
+		case string:
+			if evaluated.Inspect() != expected && evaluated.Inspect() != "ERROR: "+expected {
+				t.Errorf("%s: want %q, got %q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestStrictIndex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][3]", "index out of range: 3, length 3"},
+		{"[1, 2, 3][-4]", "index out of range: -4, length 3"},
+		{`"abc"[5]`, "index out of range: 5, length 3"},
+		{"[1, 2, 3][1:10]", "[2, 3]"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Runtime().StrictIndex = true
+		evaluated := Eval(program, env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated.Inspect() != expected && evaluated.Inspect() != "ERROR: "+expected {
+				t.Errorf("%s: want %q, got %q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestConcatenationAndSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2] + [3]", "[1, 2, 3]"},
+		{"[] + []", "[]"},
+		{"let a = [1]; let b = a + [2]; a", "[1]"},
+		{`{"a": 1, "b": 2} + {"b": 3, "c": 4}`, "{a: 1, b: 3, c: 4}"},
+		{`let h = {"a": 1}; h + {"a": 2}; h`, "{a: 1}"},
+		{"let a = [2, 3]; [1, ...a, 4]", "[1, 2, 3, 4]"},
+		{"[...[], ...[1]]", "[1]"},
+		{"let add = fn(x, y, z) { x + y + z }; add(...[1, 2], 3)", "6"},
+		{"len(...[[1, 2]])", "2"},
+		{`let h = {"b": 2}; {"a": 1, ...h, "c": 3}`, "{a: 1, b: 2, c: 3}"},
+		{`{"a": 1, ...{"a": 2}}`, "{a: 2}"},
+		{"[1] + {}", "ERROR: type mismatch: ARRAY + HASH"},
+		{"[...1]", "ERROR: spread expects ARRAY, got INTEGER"},
+		{"{...[1]}", "ERROR: spread in hash literal expects HASH, got ARRAY"},
+		{"let a = ...[1]", "ERROR: spread is only allowed in array literals, hash literals and call arguments"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `
 let two = "two";
@@ -506,26 +1424,112 @@ let two = "two";
 		t.Fatalf("want hash, got %T", evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("unexpected number of pairs, want 6, got %d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("unexpected number of pairs, want 6, got %d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for i, pair := range expected {
+		value, ok := result.Get(pair.key)
 		if !ok {
 			t.Error("no pair for given key")
+			continue
 		}
 
-		testIntegerObject(t, pair.Value, expectedValue)
+		testIntegerObject(t, value, pair.value)
+
+		if key := result.Pairs()[i].Key; key.Inspect() != pair.key.Inspect() {
+			t.Errorf("want key %s at position %d, got %s", pair.key.Inspect(), i, key.Inspect())
+		}
+	}
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, 3: 3, true: 4}`, "{b: 1, a: 2, 3: 3, true: 4}"},
+		{`{"b": 1, "a": 2, "b": 3}`, "{b: 3, a: 2}"},
+		{`{"z": 1, "y": 2}.len()`, "2"},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Fatalf("want %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{`[1, "a", [true]] == [1, "a", [true]]`, true},
+		{`[1, "a", [true]] == [1, "a", [false]]`, false},
+		{"[] == []", true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{"{} == {}", true},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{`[1] == "[1]"`, false},
+		{`1 == "1"`, false},
+		{"let f = fn() { 1 }; f == f", true},
+		{"fn() { 1 } == fn() { 1 }", false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`{[1, 2]: "a"}[[1, 2]]`, "a"},
+		{`{[1, 2]: "a"}[[2, 1]]`, nil},
+		{`{[1, [2, "x"]]: "a"}[[1, [2, "x"]]]`, "a"},
+		{`{[1, 2]: "a", [1, 2]: "b"}.len()`, 1},
+		{`{[]: "empty"}[[]]`, "empty"},
+		{`{[1, fn() {}]: 1}`, "unhashable: ARRAY"},
+		{`{}[[{}]]`, "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if evaluated.Inspect() != expected && evaluated.Inspect() != "ERROR: "+expected {
+				t.Errorf("want %q, got %q", expected, evaluated.Inspect())
+			}
+		}
 	}
 }
 
@@ -554,6 +1558,110 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestContextDeadline(t *testing.T) {
+	// runs for ages without going deeper than the call depth cap
+	input := "let spin = fn(n) { if (n > 0) { spin(n - 1); spin(n - 1) } }; spin(50);"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	env := object.NewEnvironment()
+	env.Runtime().Context = ctx
+
+	testAbortError(t, Eval(testParseProgram(input), env), context.DeadlineExceeded)
+}
+
+func TestContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	env := object.NewEnvironment()
+	env.Runtime().Context = ctx
+
+	testAbortError(t, Eval(testParseProgram("1 + 2"), env), context.Canceled)
+}
+
+func TestRuntimeLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		runtime  *object.Runtime
+		expected error
+	}{
+		{
+			"let loop = fn() { loop() }; loop();",
+			&object.Runtime{MaxSteps: 1000},
+			object.ErrStepLimit,
+		},
+		{
+			"let loop = fn() { loop() }; loop();",
+			&object.Runtime{MaxDepth: 100},
+			object.ErrDepthLimit,
+		},
+		{
+			"let count = fn(n) { if (n == 0) { 0 } else { count(n - 1) } }; count(100);",
+			&object.Runtime{MaxDepth: 100},
+			object.ErrDepthLimit,
+		},
+		{
+			`let grow = fn(s) { grow(s + s) }; grow("monkey");`,
+			&object.Runtime{MaxAlloc: 1 << 20},
+			object.ErrAllocLimit,
+		},
+		{
+			"let grow = fn(arr) { grow(push(arr, arr)) }; grow([]);",
+			&object.Runtime{MaxAlloc: 1 << 20},
+			object.ErrAllocLimit,
+		},
+		{
+			`let grow = fn(n) { {"n": n, "next": grow(n + 1)} }; grow(0);`,
+			&object.Runtime{MaxAlloc: 1 << 10, MaxDepth: 1 << 10},
+			object.ErrAllocLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironmentWithRuntime(tt.runtime)
+		testAbortError(t, Eval(testParseProgram(tt.input), env), tt.expected)
+	}
+}
+
+func TestRuntimeLimitsNotReached(t *testing.T) {
+	input := "let count = fn(n) { if (n == 0) { 0 } else { count(n - 1) } }; count(99);"
+
+	runtime := &object.Runtime{MaxSteps: 10000, MaxDepth: 100}
+	env := object.NewEnvironmentWithRuntime(runtime)
+
+	testIntegerObject(t, Eval(testParseProgram(input), env), 0)
+
+	if runtime.Steps() == 0 {
+		t.Errorf("runtime steps are not accounted")
+	}
+}
+
+func TestAllocationAccounting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1 + 2", 0},
+		{`"abc"`, 3},
+		{`"abc" + "de"`, 3 + 2 + 5},
+		{"[1, 2, 3]", 3 * 16},
+		{"push([1], 2)", 1*16 + 2*16},
+		{`{1: 2}`, 56},
+		{`first(["abc"])`, 3 + 16},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		Eval(testParseProgram(tt.input), env)
+
+		if allocated := env.Runtime().Allocated(); allocated != tt.expected {
+			t.Errorf("%s: want %d bytes allocated, got %d", tt.input, tt.expected, allocated)
+		}
+	}
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -593,6 +1701,23 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	return true
 }
 
+func testAbortError(t *testing.T, obj object.Object, expected error) bool {
+	t.Helper()
+
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("want error, got %T", obj)
+		return false
+	}
+
+	if !errors.Is(errObj.Cause, expected) {
+		t.Errorf("want error caused by %q, got %q", expected, errObj.Message)
+		return false
+	}
+
+	return true
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("want null, got %T", obj)
@@ -0,0 +1,169 @@
+package evaluator
+
+import (
+	"errors"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// fileBuiltins give access to files only through Runtime.FS, paths are
+// slash-separated and relative to its root.
+func fileBuiltins() []*object.Builtin {
+	return []*object.Builtin{
+		{
+			Name:   "read_file",
+			Doc:    "Returns contents of a file.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				fsys, name, err := resolvePath(runtime, args[0])
+				if err != nil {
+					return err
+				}
+
+				info, statErr := fs.Stat(fsys, name)
+				if statErr != nil {
+					return newError("cannot read file: %s", statErr)
+				}
+
+				if err := runtime.AllocateBytes(info.Size()); err != nil {
+					return err
+				}
+
+				data, readErr := fs.ReadFile(fsys, name)
+				if readErr != nil {
+					return newError("cannot read file: %s", readErr)
+				}
+
+				return &object.String{Value: string(data)}
+			},
+		},
+		{
+			Name:   "write_file",
+			Doc:    "Writes a string to a file, replacing its contents.",
+			Params: []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				fsys, name, err := resolvePath(runtime, args[0])
+				if err != nil {
+					return err
+				}
+
+				writable, ok := fsys.(object.WriteFS)
+				if !ok {
+					return newError("file system is read-only")
+				}
+
+				if err := writable.WriteFile(name, []byte(args[1].(*object.String).Value)); err != nil {
+					return newError("cannot write file: %s", err)
+				}
+
+				return NULL
+			},
+		},
+		{
+			Name:   "list_dir",
+			Doc:    "Returns names of entries of a directory in sorted order.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				fsys, name, err := resolvePath(runtime, args[0])
+				if err != nil {
+					return err
+				}
+
+				entries, readErr := fs.ReadDir(fsys, name)
+				if readErr != nil {
+					return newError("cannot list directory: %s", readErr)
+				}
+
+				names := make([]string, len(entries))
+				for i, entry := range entries {
+					names[i] = entry.Name()
+				}
+
+				return allocate(runtime, stringArray(names))
+			},
+		},
+		{
+			Name:   "exists",
+			Doc:    "Reports whether a file or a directory exists.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				fsys, name, err := resolvePath(runtime, args[0])
+				if err != nil {
+					return err
+				}
+
+				_, statErr := fs.Stat(fsys, name)
+				if errors.Is(statErr, fs.ErrNotExist) {
+					return FALSE
+				}
+				if statErr != nil {
+					return newError("cannot check file: %s", statErr)
+				}
+
+				return TRUE
+			},
+		},
+		{
+			Name:     "path_join",
+			Doc:      "Joins path elements with slashes and cleans the result.",
+			Params:   []object.ObjectType{object.STRING_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				elements := make([]string, len(args))
+				for i, arg := range args {
+					elements[i] = arg.(*object.String).Value
+				}
+
+				return allocate(runtime, &object.String{Value: path.Join(elements...)})
+			},
+		},
+		{
+			Name:   "path_base",
+			Doc:    "Returns the last element of a path.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return allocate(runtime, &object.String{Value: path.Base(args[0].(*object.String).Value)})
+			},
+		},
+		{
+			Name:   "path_dir",
+			Doc:    "Returns all but the last element of a path.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return allocate(runtime, &object.String{Value: path.Dir(args[0].(*object.String).Value)})
+			},
+		},
+		{
+			Name:   "path_ext",
+			Doc:    "Returns the extension of the last element of a path, including the dot.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return allocate(runtime, &object.String{Value: path.Ext(args[0].(*object.String).Value)})
+			},
+		},
+	}
+}
+
+// resolvePath turns a script path into a name valid in the runtime file
+// system: "/" refers to its root and paths can't go above it.
+func resolvePath(runtime *object.Runtime, arg object.Object) (fs.FS, string, *object.Error) {
+	if runtime.FS == nil {
+		return nil, "", newError("file system access is not allowed")
+	}
+
+	original := arg.(*object.String).Value
+
+	name := strings.TrimPrefix(path.Clean("/"+original), "/")
+	if name == "" {
+		name = "."
+	}
+
+	// Clean of a rooted path drops leading "..", going above is an error
+	if clean := path.Clean(original); clean == ".." || strings.HasPrefix(clean, "../") || !fs.ValidPath(name) {
+		return nil, "", newError("invalid path %q", original)
+	}
+
+	return runtime.FS, name, nil
+}
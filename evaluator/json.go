@@ -0,0 +1,209 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxJSONDepth limits nesting of decoded documents, decoding is recursive
+const maxJSONDepth = 1000
+
+func jsonBuiltins() []*object.Builtin {
+	return []*object.Builtin{
+		{
+			Name:     "json_encode",
+			Doc:      "Encodes a value as JSON, keys of hashes are kept in insertion order, pretty-printed if the second argument is true.",
+			Params:   []object.ObjectType{object.ANY_OBJ, object.BOOLEAN_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 2 {
+					return newError("`json_encode` accepts at most 2 arguments, got %d", len(args))
+				}
+
+				var out bytes.Buffer
+				if err := encodeJSON(&out, args[0]); err != nil {
+					return err
+				}
+
+				if len(args) == 2 && args[1] == TRUE {
+					var pretty bytes.Buffer
+					json.Indent(&pretty, out.Bytes(), "", "  ")
+					out = pretty
+				}
+
+				return allocate(runtime, &object.String{Value: out.String()})
+			},
+		},
+		{
+			Name:   "json_decode",
+			Doc:    "Decodes a JSON document, objects become hashes keeping the order of keys.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				decoder := json.NewDecoder(strings.NewReader(args[0].(*object.String).Value))
+				decoder.UseNumber()
+
+				value := decodeJSON(runtime, decoder, 0)
+				if isError(value) {
+					return value
+				}
+
+				if _, err := decoder.Token(); err != io.EOF {
+					return newError("malformed JSON: unexpected data after the value")
+				}
+
+				return value
+			},
+		},
+	}
+}
+
+func encodeJSON(out *bytes.Buffer, obj object.Object) *object.Error {
+	switch obj := obj.(type) {
+
+	case *object.Null:
+		out.WriteString("null")
+
+	case *object.Boolean, *object.Integer:
+		out.WriteString(obj.Inspect())
+
+	case *object.String:
+		writeJSONString(out, obj.Value)
+
+	case *object.Array:
+		out.WriteByte('[')
+		for i, element := range obj.Elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := encodeJSON(out, element); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+
+	case *object.Hash:
+		out.WriteByte('{')
+		for i, pair := range obj.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("cannot encode hash key of type %s as JSON, keys should be STRING", pair.Key.Type())
+			}
+
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONString(out, key.Value)
+			out.WriteByte(':')
+			if err := encodeJSON(out, pair.Value); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+
+	default:
+		return newError("cannot encode %s as JSON", obj.Type())
+	}
+
+	return nil
+}
+
+// writeJSONString quotes the string without escaping HTML characters as
+// json.Marshal does
+func writeJSONString(out *bytes.Buffer, value string) {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+
+	out.Truncate(out.Len() - 1) // newline written by Encode
+}
+
+// decodeJSON decodes the next value from the token stream, objects are read
+// token by token to keep the order of keys
+func decodeJSON(runtime *object.Runtime, decoder *json.Decoder, depth int) object.Object {
+	if depth > maxJSONDepth {
+		return newError("malformed JSON: nesting is deeper than %d", maxJSONDepth)
+	}
+
+	tok, err := decoder.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return newError("malformed JSON: %s", err)
+	}
+
+	switch tok := tok.(type) {
+
+	case nil:
+		return NULL
+
+	case bool:
+		return nativeBoolToBooleanObject(tok)
+
+	case json.Number:
+		value, err := strconv.ParseInt(string(tok), 10, 64)
+		if err != nil {
+			return newError("cannot decode JSON number %s as INTEGER", tok)
+		}
+		return &object.Integer{Value: value}
+
+	case string:
+		return allocate(runtime, &object.String{Value: tok})
+
+	case json.Delim:
+		if tok == '[' {
+			elements := []object.Object{}
+			for decoder.More() {
+				element := decodeJSON(runtime, decoder, depth+1)
+				if isError(element) {
+					return element
+				}
+				elements = append(elements, element)
+			}
+
+			if err := closeJSONDelim(decoder); err != nil {
+				return err
+			}
+			return allocate(runtime, &object.Array{Elements: elements})
+		}
+
+		hash := &object.Hash{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return newError("malformed JSON: %s", err)
+			}
+
+			value := decodeJSON(runtime, decoder, depth+1)
+			if isError(value) {
+				return value
+			}
+			hash.Set(&object.String{Value: key.(string)}, value)
+		}
+
+		if err := closeJSONDelim(decoder); err != nil {
+			return err
+		}
+		return allocate(runtime, hash)
+
+	default:
+		return newError("malformed JSON: unexpected %v", tok)
+	}
+}
+
+// closeJSONDelim reads the closing bracket or brace of an array or an object
+func closeJSONDelim(decoder *json.Decoder) *object.Error {
+	if _, err := decoder.Token(); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return newError("malformed JSON: %s", err)
+	}
+
+	return nil
+}
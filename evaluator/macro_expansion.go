@@ -51,8 +51,14 @@ func addMacro(stmt ast.Statement, env *object.Environment) {
 	env.Set(letStmt.Name.Value, macro)
 }
 
-func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
-	return ast.Modify(program, func(node ast.Node) ast.Node {
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if err != nil {
+			return node
+		}
+
 		callExpression, ok := node.(*ast.CallExpression)
 		if !ok {
 			return node
@@ -67,14 +73,21 @@ func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
 		evalEnv := extendMacroEnv(macro, args)
 
 		evaluated := Eval(macro.Body, evalEnv)
+		if evalErr, ok := evaluated.(*object.Error); ok {
+			err = evalErr
+			return node
+		}
 
 		quote, ok := evaluated.(*object.Quote)
 		if !ok {
-			panic("macro must return AST node")
+			err = newError("macro must return AST node")
+			return node
 		}
 
 		return quote.Node
 	})
+
+	return expanded, err
 }
 
 func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
@@ -106,7 +106,10 @@ func TestExpandMacros(t *testing.T) {
 
 		env := object.NewEnvironment()
 		DefineMacros(program, env)
-		expanded := ExpandMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Message)
+		}
 
 		if expanded.String() != expected.String() {
 			t.Errorf("want %q, got %q", expected.String(), expanded.String())
@@ -114,6 +117,26 @@ func TestExpandMacros(t *testing.T) {
 	}
 }
 
+func TestExpandMacrosAborted(t *testing.T) {
+	input := `
+	let loop = macro() { let f = fn() { f() }; f(); };
+	loop();
+	`
+
+	program := testParseProgram(input)
+	env := object.NewEnvironmentWithRuntime(&object.Runtime{MaxSteps: 1000})
+	DefineMacros(program, env)
+
+	_, err := ExpandMacros(program, env)
+	if err == nil {
+		t.Fatalf("want error, got nil")
+	}
+
+	if err.Cause != object.ErrStepLimit {
+		t.Errorf("want step limit error, got %q", err.Message)
+	}
+}
+
 func testParseProgram(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
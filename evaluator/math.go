@@ -0,0 +1,312 @@
+package evaluator
+
+import (
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"math"
+)
+
+// maxSqrt is the integer square root of math.MaxInt64
+const maxSqrt = 3037000499
+
+// mathBuiltins only work with integers, so rounding builtins divide
+func mathBuiltins() []*object.Builtin {
+	return []*object.Builtin{
+		{
+			Name:   "abs",
+			Doc:    "Returns the absolute value of an integer.",
+			Params: []object.ObjectType{object.INTEGER_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				value := args[0].(*object.Integer).Value
+				if value == math.MinInt64 {
+					return newError("integer overflow")
+				}
+				if value < 0 {
+					return &object.Integer{Value: -value}
+				}
+				return args[0]
+			},
+		},
+		{
+			Name:     "min",
+			Doc:      "Returns the smallest argument or element of a single array argument.",
+			Params:   []object.ObjectType{object.ANY_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return extremum("min", args, func(a, b object.Object) (bool, object.Object) { return lessThan(b, a) })
+			},
+		},
+		{
+			Name:     "max",
+			Doc:      "Returns the largest argument or element of a single array argument.",
+			Params:   []object.ObjectType{object.ANY_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return extremum("max", args, lessThan)
+			},
+		},
+		{
+			Name:   "pow",
+			Doc:    "Raises the base to a non-negative power.",
+			Params: []object.ObjectType{object.INTEGER_OBJ, object.INTEGER_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				base := args[0].(*object.Integer).Value
+				exponent := args[1].(*object.Integer).Value
+
+				if exponent < 0 {
+					return newError("`pow` exponent should be non-negative, got %d", exponent)
+				}
+
+				result := int64(1)
+				for exponent > 0 {
+					var ok bool
+					if exponent&1 == 1 {
+						if result, ok = multiply(result, base); !ok {
+							return newError("integer overflow")
+						}
+					}
+
+					exponent >>= 1
+					if exponent > 0 {
+						if base, ok = multiply(base, base); !ok {
+							return newError("integer overflow")
+						}
+					}
+				}
+
+				return &object.Integer{Value: result}
+			},
+		},
+		{
+			Name:   "sqrt",
+			Doc:    "Returns the integer square root, rounded down.",
+			Params: []object.ObjectType{object.INTEGER_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				value := args[0].(*object.Integer).Value
+				if value < 0 {
+					return newError("`sqrt` of negative number %d", value)
+				}
+
+				// floats are off by one for large values
+				root := min(int64(math.Sqrt(float64(value))), maxSqrt)
+				for root*root > value {
+					root--
+				}
+				for root < maxSqrt && (root+1)*(root+1) <= value {
+					root++
+				}
+
+				return &object.Integer{Value: root}
+			},
+		},
+		{
+			Name:     "floor",
+			Doc:      "Divides rounding towards negative infinity, the divisor is 1 by default.",
+			Params:   []object.ObjectType{object.INTEGER_OBJ, object.INTEGER_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return divide("floor", args, func(quotient, remainder, divisor int64) int64 {
+					if remainder != 0 && (remainder < 0) != (divisor < 0) {
+						return quotient - 1
+					}
+					return quotient
+				})
+			},
+		},
+		{
+			Name:     "ceil",
+			Doc:      "Divides rounding towards positive infinity, the divisor is 1 by default.",
+			Params:   []object.ObjectType{object.INTEGER_OBJ, object.INTEGER_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return divide("ceil", args, func(quotient, remainder, divisor int64) int64 {
+					if remainder != 0 && (remainder < 0) == (divisor < 0) {
+						return quotient + 1
+					}
+					return quotient
+				})
+			},
+		},
+		{
+			Name:     "round",
+			Doc:      "Divides rounding to the nearest integer, halves away from zero, the divisor is 1 by default.",
+			Params:   []object.ObjectType{object.INTEGER_OBJ, object.INTEGER_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return divide("round", args, func(quotient, remainder, divisor int64) int64 {
+					// |remainder| >= |divisor| / 2 without overflows
+					r, d := absUint(remainder), absUint(divisor)
+					if r < d-r {
+						return quotient
+					}
+					if (remainder < 0) != (divisor < 0) {
+						return quotient - 1
+					}
+					return quotient + 1
+				})
+			},
+		},
+		{
+			Name:   "clamp",
+			Doc:    "Limits the integer to the range from low to high, both included.",
+			Params: []object.ObjectType{object.INTEGER_OBJ, object.INTEGER_OBJ, object.INTEGER_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				value := args[0].(*object.Integer).Value
+				low := args[1].(*object.Integer).Value
+				high := args[2].(*object.Integer).Value
+
+				if low > high {
+					return newError("`clamp` low bound %d is greater than high bound %d", low, high)
+				}
+
+				return &object.Integer{Value: min(max(value, low), high)}
+			},
+		},
+		{
+			Name:   "gcd",
+			Doc:    "Returns the greatest common divisor of two integers, always non-negative.",
+			Params: []object.ObjectType{object.INTEGER_OBJ, object.INTEGER_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				a := absUint(args[0].(*object.Integer).Value)
+				b := absUint(args[1].(*object.Integer).Value)
+
+				for b != 0 {
+					a, b = b, a%b
+				}
+
+				if a > math.MaxInt64 {
+					return newError("integer overflow")
+				}
+				return &object.Integer{Value: int64(a)}
+			},
+		},
+		{
+			Name:     "rand_int",
+			Doc:      "Returns a random integer from 0 to n or from low to high, the upper bound is excluded.",
+			Params:   []object.ObjectType{object.INTEGER_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) == 0 || len(args) > 2 {
+					return newError("`rand_int` accepts 1 or 2 arguments, got %d", len(args))
+				}
+
+				low, high := int64(0), args[0].(*object.Integer).Value
+				if len(args) == 2 {
+					low, high = high, args[1].(*object.Integer).Value
+				}
+
+				if low >= high {
+					return newError("`rand_int` range is empty: from %d to %d", low, high)
+				}
+
+				offset := runtime.Random().Uint64N(distance(low, high))
+				return &object.Integer{Value: low + int64(offset)}
+			},
+		},
+		{
+			Name:   "shuffle",
+			Doc:    "Returns a new array with elements in random order.",
+			Params: []object.ObjectType{object.ARRAY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				arr := args[0].(*object.Array)
+
+				elements := make([]object.Object, len(arr.Elements))
+				copy(elements, arr.Elements)
+				runtime.Random().Shuffle(len(elements), func(i, j int) {
+					elements[i], elements[j] = elements[j], elements[i]
+				})
+
+				return allocate(runtime, &object.Array{Elements: elements})
+			},
+		},
+		{
+			Name:   "choice",
+			Doc:    "Returns a random element of a non-empty array.",
+			Params: []object.ObjectType{object.ARRAY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				elements := args[0].(*object.Array).Elements
+				if len(elements) == 0 {
+					return newError("`choice` of empty array")
+				}
+
+				return elements[runtime.Random().IntN(len(elements))]
+			},
+		},
+	}
+}
+
+// extremum returns the argument not preceded by any other in the ordering of
+// less, a single array argument is replaced with its elements
+func extremum(name string, args []object.Object, less func(a, b object.Object) (bool, object.Object)) object.Object {
+	if len(args) == 1 {
+		if arr, ok := args[0].(*object.Array); ok {
+			args = arr.Elements
+		}
+	}
+
+	if len(args) == 0 {
+		return newError("`%s` of no values", name)
+	}
+
+	result := args[0]
+	for _, arg := range args[1:] {
+		replace, err := less(result, arg)
+		if err != nil {
+			return err
+		}
+		if replace {
+			result = arg
+		}
+	}
+
+	return result
+}
+
+// divide implements rounding divisions, round adjusts the quotient truncated
+// towards zero
+func divide(name string, args []object.Object, round func(quotient, remainder, divisor int64) int64) object.Object {
+	if len(args) > 2 {
+		return newError("`%s` accepts at most 2 arguments, got %d", name, len(args))
+	}
+
+	dividend, divisor := args[0].(*object.Integer).Value, int64(1)
+	if len(args) == 2 {
+		divisor = args[1].(*object.Integer).Value
+	}
+
+	if divisor == 0 {
+		return newError("division by zero")
+	}
+
+	if dividend == math.MinInt64 && divisor == -1 {
+		return newError("integer overflow")
+	}
+
+	return &object.Integer{Value: round(dividend/divisor, dividend%divisor, divisor)}
+}
+
+// multiply reports false if the product overflows
+func multiply(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+
+	return product, true
+}
+
+func absUint(value int64) uint64 {
+	if value < 0 {
+		return uint64(-(value + 1)) + 1
+	}
+	return uint64(value)
+}
+
+// distance returns high - low for low <= high, it's unsigned because the
+// distance between bounds may not fit into int64
+func distance(low, high int64) uint64 {
+	return uint64(high - low)
+}
@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+)
+
+// methods are builtins callable with dot syntax, e.g. `"abc".upper()`, they
+// get the receiver as the first argument
+var methods map[object.ObjectType]map[string]*object.Builtin
+
+// initialized in init to break the initialization cycle through Eval
+func init() {
+	withGroups(builtins, collectionBuiltins())
+
+	methods = map[object.ObjectType]map[string]*object.Builtin{
+		object.STRING_OBJ: {
+			"chars":       builtins["chars"],
+			"contains":    builtins["contains"],
+			"ends_with":   builtins["ends_with"],
+			"index_of":    builtins["index_of"],
+			"len":         builtins["len"],
+			"lower":       builtins["lower"],
+			"pad":         builtins["pad"],
+			"repeat":      builtins["repeat"],
+			"replace":     builtins["replace"],
+			"reverse":     builtins["reverse"],
+			"split":       builtins["split"],
+			"starts_with": builtins["starts_with"],
+			"trim":        builtins["trim"],
+			"upper":       builtins["upper"],
+		},
+		object.ARRAY_OBJ: {
+			"all":       builtins["all"],
+			"any":       builtins["any"],
+			"contains":  builtins["contains"],
+			"each":      builtins["each"],
+			"enumerate": builtins["enumerate"],
+			"filter":    builtins["filter"],
+			"find":      builtins["find"],
+			"first":     builtins["first"],
+			"index_of":  builtins["index_of"],
+			"join":      builtins["join"],
+			"last":      builtins["last"],
+			"len":       builtins["len"],
+			"map":       builtins["map"],
+			"max":       builtins["max"],
+			"min":       builtins["min"],
+			"push":      builtins["push"],
+			"reduce":    builtins["reduce"],
+			"rest":      builtins["rest"],
+			"reverse":   builtins["reverse"],
+			"sort":      builtins["sort"],
+			"unique":    builtins["unique"],
+			"zip":       builtins["zip"],
+		},
+		object.REGEX_OBJ: regexMethods(),
+		object.TIME_OBJ:  timeMethods(),
+		object.HASH_OBJ: {
+			"contains": builtins["contains"],
+			"keys":     builtins["keys"],
+			"len":      builtins["len"],
+			"values":   builtins["values"],
+		},
+	}
+}
+
+func evalMemberExpression(obj object.Object, member string) object.Object {
+	switch obj := obj.(type) {
+
+	case *object.Host:
+		if method, ok := obj.Method(member); ok {
+			return method
+		}
+		return newError("unknown member of %s: %s", obj.TypeName, member)
+
+	case *object.Module:
+		if value, ok := obj.Exports[member]; ok {
+			return value
+		}
+		return newError("module %s has no export %s", obj.Name, member)
+
+	case *object.Hash:
+		// fields shadow methods, missing fields are null as with indexing
+		if value, ok := obj.Get(&object.String{Value: member}); ok {
+			return value
+		}
+		if method, ok := methods[obj.Type()][member]; ok {
+			return method.Bind(obj)
+		}
+		return NULL
+
+	default:
+		if method, ok := methods[obj.Type()][member]; ok {
+			return method.Bind(obj)
+		}
+		return newError("unknown method of %s: %s", obj.Type(), member)
+	}
+}
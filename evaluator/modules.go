@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"errors"
+	"github.com/lancelote/writing-an-interpreter-in-go/ast"
+	"github.com/lancelote/writing-an-interpreter-in-go/lexer"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"github.com/lancelote/writing-an-interpreter-in-go/parser"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// moduleExt is added to import paths without an extension
+const moduleExt = ".mk"
+
+// evalImportExpression loads the module on first import, later imports of the
+// same file return the same module
+func evalImportExpression(node *ast.ImportExpression, env *object.Environment) object.Object {
+	runtime := env.Runtime()
+
+	name, err := findModule(runtime, node.Path)
+	if err != nil {
+		return err
+	}
+
+	if module, ok := runtime.Module(name); ok {
+		return module
+	}
+
+	if err := runtime.BeginImport(name); err != nil {
+		return err
+	}
+
+	module, err := loadModule(runtime, name)
+	runtime.EndImport(module)
+
+	if err != nil {
+		return err
+	}
+	return module
+}
+
+// findModule resolves the import path to the first existing file in the
+// directories of Runtime.ModulePath
+func findModule(runtime *object.Runtime, importPath string) (string, *object.Error) {
+	if runtime.ModuleFS == nil {
+		return "", newError("imports are not allowed")
+	}
+
+	if path.Ext(importPath) == "" {
+		importPath += moduleExt
+	}
+
+	dirs := runtime.ModulePath
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	for _, dir := range dirs {
+		name := path.Join(dir, importPath)
+		if !fs.ValidPath(name) {
+			return "", newError("invalid module path %q", importPath)
+		}
+
+		_, statErr := fs.Stat(runtime.ModuleFS, name)
+		if errors.Is(statErr, fs.ErrNotExist) {
+			continue
+		}
+		if statErr != nil {
+			return "", newError("cannot import %q: %s", importPath, statErr)
+		}
+
+		return name, nil
+	}
+
+	return "", newError("module %q not found", importPath)
+}
+
+// loadModule evaluates the module in its own environment, macros defined in
+// the module are only available to it
+func loadModule(runtime *object.Runtime, name string) (*object.Module, *object.Error) {
+	source, readErr := fs.ReadFile(runtime.ModuleFS, name)
+	if readErr != nil {
+		return nil, newError("cannot import %q: %s", name, readErr)
+	}
+
+	p := parser.New(lexer.New(lexer.StripShebang(string(source))))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("cannot parse module %q: %s", name, strings.Join(p.Errors(), "; "))
+	}
+
+	macroEnv := object.NewEnvironmentWithRuntime(runtime)
+	DefineMacros(program, macroEnv)
+	expanded, err := ExpandMacros(program, macroEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	env := object.NewEnvironmentWithRuntime(runtime)
+	if result := Eval(expanded, env); isError(result) {
+		return nil, result.(*object.Error)
+	}
+
+	return &object.Module{Name: name, Exports: env.Exports()}, nil
+}
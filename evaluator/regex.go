@@ -0,0 +1,179 @@
+package evaluator
+
+import (
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"regexp"
+	"strings"
+)
+
+func regexBuiltins() []*object.Builtin {
+	return []*object.Builtin{
+		{
+			Name:   "regex",
+			Doc:    "Compiles a regular expression in Go syntax, see https://pkg.go.dev/regexp/syntax.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				re, err := regexp.Compile(args[0].(*object.String).Value)
+				if err != nil {
+					return newError("invalid regex: %s", err)
+				}
+
+				return &object.Regex{Value: re}
+			},
+		},
+	}
+}
+
+// regexMethods take the regex as the receiver, e.g. `regex("a+").match(s)`
+func regexMethods() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"match": {
+			Name:   "match",
+			Doc:    "Reports whether the string contains a match.",
+			Params: []object.ObjectType{object.REGEX_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				re := args[0].(*object.Regex).Value
+				return nativeBoolToBooleanObject(re.MatchString(args[1].(*object.String).Value))
+			},
+		},
+		"find_all": {
+			Name:     "find_all",
+			Doc:      "Returns all matches in the string, at most n if it's given.",
+			Params:   []object.ObjectType{object.REGEX_OBJ, object.STRING_OBJ, object.INTEGER_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 3 {
+					return newError("`find_all` accepts at most 3 arguments, got %d", len(args))
+				}
+
+				re := args[0].(*object.Regex).Value
+				limit := regexLimit(args)
+
+				return allocate(runtime, stringArray(re.FindAllString(args[1].(*object.String).Value, limit)))
+			},
+		},
+		"captures": {
+			Name:   "captures",
+			Doc:    "Returns the first match followed by its groups, null for groups that didn't participate, or null if there is no match.",
+			Params: []object.ObjectType{object.REGEX_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				re := args[0].(*object.Regex).Value
+				str := args[1].(*object.String).Value
+
+				groups := captureGroups(re, str)
+				if groups == nil {
+					return NULL
+				}
+
+				return allocate(runtime, &object.Array{Elements: groups})
+			},
+		},
+		"named_captures": {
+			Name:   "named_captures",
+			Doc:    "Returns a hash of named groups of the first match, null for groups that didn't participate, or null if there is no match.",
+			Params: []object.ObjectType{object.REGEX_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				re := args[0].(*object.Regex).Value
+				str := args[1].(*object.String).Value
+
+				groups := captureGroups(re, str)
+				if groups == nil {
+					return NULL
+				}
+
+				hash := &object.Hash{}
+				for i, name := range re.SubexpNames() {
+					if name != "" {
+						hash.Set(&object.String{Value: name}, groups[i])
+					}
+				}
+
+				return allocate(runtime, hash)
+			},
+		},
+		"replace": {
+			Name:   "replace",
+			Doc:    "Replaces every match with a string, where $1 or ${name} refer to groups, or with the result of a function called with the matched string.",
+			Params: []object.ObjectType{object.REGEX_OBJ, object.STRING_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				re := args[0].(*object.Regex).Value
+				str := args[1].(*object.String).Value
+
+				if replacement, ok := args[2].(*object.String); ok {
+					return allocate(runtime, &object.String{Value: re.ReplaceAllString(str, replacement.Value)})
+				}
+
+				var out strings.Builder
+				last := 0
+				for _, loc := range re.FindAllStringIndex(str, -1) {
+					replaced := applyFunction(runtime, args[2], []object.Object{&object.String{Value: str[loc[0]:loc[1]]}})
+					if isError(replaced) {
+						return replaced
+					}
+
+					text, ok := replaced.(*object.String)
+					if !ok {
+						return newError("`replace` function should return STRING, got %s", replaced.Type())
+					}
+
+					out.WriteString(str[last:loc[0]])
+					out.WriteString(text.Value)
+					last = loc[1]
+				}
+				out.WriteString(str[last:])
+
+				return allocate(runtime, &object.String{Value: out.String()})
+			},
+		},
+		"split": {
+			Name:     "split",
+			Doc:      "Splits the string around matches, into at most n parts if it's given.",
+			Params:   []object.ObjectType{object.REGEX_OBJ, object.STRING_OBJ, object.INTEGER_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 3 {
+					return newError("`split` accepts at most 3 arguments, got %d", len(args))
+				}
+
+				re := args[0].(*object.Regex).Value
+				limit := regexLimit(args)
+
+				return allocate(runtime, stringArray(re.Split(args[1].(*object.String).Value, limit)))
+			},
+		},
+	}
+}
+
+// regexLimit returns the optional third argument limiting the number of
+// results, negative means no limit as in regexp
+func regexLimit(args []object.Object) int {
+	if len(args) < 3 {
+		return -1
+	}
+
+	limit := args[2].(*object.Integer).Value
+	if limit < 0 || limit > int64(^uint(0)>>1) {
+		return -1
+	}
+	return int(limit)
+}
+
+// captureGroups returns the first match and its groups, nil if there is no
+// match
+func captureGroups(re *regexp.Regexp, str string) []object.Object {
+	loc := re.FindStringSubmatchIndex(str)
+	if loc == nil {
+		return nil
+	}
+
+	groups := make([]object.Object, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] < 0 {
+			groups[i] = NULL
+			continue
+		}
+		groups[i] = &object.String{Value: str[loc[2*i]:loc[2*i+1]]}
+	}
+
+	return groups
+}
@@ -0,0 +1,432 @@
+package evaluator
+
+import (
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func stringBuiltins() []*object.Builtin {
+	return []*object.Builtin{
+		{
+			Name:     "split",
+			Doc:      "Splits a string by the separator or by whitespace if it's omitted.",
+			Params:   []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 2 {
+					return newError("`split` accepts at most 2 arguments, got %d", len(args))
+				}
+
+				str := args[0].(*object.String).Value
+
+				var parts []string
+				if len(args) == 2 {
+					parts = strings.Split(str, args[1].(*object.String).Value)
+				} else {
+					parts = strings.Fields(str)
+				}
+
+				return allocate(runtime, stringArray(parts))
+			},
+		},
+		{
+			Name:     "join",
+			Doc:      "Joins elements of an array with the separator, non-string elements are inspected.",
+			Params:   []object.ObjectType{object.ARRAY_OBJ, object.STRING_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 2 {
+					return newError("`join` accepts at most 2 arguments, got %d", len(args))
+				}
+
+				separator := ""
+				if len(args) == 2 {
+					separator = args[1].(*object.String).Value
+				}
+
+				parts := []string{}
+				for _, element := range args[0].(*object.Array).Elements {
+					parts = append(parts, element.Inspect())
+				}
+
+				return allocate(runtime, &object.String{Value: strings.Join(parts, separator)})
+			},
+		},
+		{
+			Name:     "trim",
+			Doc:      "Removes leading and trailing whitespace or runes of the cutset.",
+			Params:   []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 2 {
+					return newError("`trim` accepts at most 2 arguments, got %d", len(args))
+				}
+
+				str := args[0].(*object.String).Value
+				if len(args) == 2 {
+					return allocate(runtime, &object.String{Value: strings.Trim(str, args[1].(*object.String).Value)})
+				}
+
+				return allocate(runtime, &object.String{Value: strings.TrimSpace(str)})
+			},
+		},
+		{
+			Name:   "lower",
+			Doc:    "Returns the string in lower case.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				str := args[0].(*object.String)
+				return allocate(runtime, &object.String{Value: strings.ToLower(str.Value)})
+			},
+		},
+		{
+			Name:   "upper",
+			Doc:    "Returns the string in upper case.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				str := args[0].(*object.String)
+				return allocate(runtime, &object.String{Value: strings.ToUpper(str.Value)})
+			},
+		},
+		{
+			Name:   "replace",
+			Doc:    "Replaces every occurrence of old with new.",
+			Params: []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				str := args[0].(*object.String).Value
+				old := args[1].(*object.String).Value
+				replacement := args[2].(*object.String).Value
+
+				return allocate(runtime, &object.String{Value: strings.ReplaceAll(str, old, replacement)})
+			},
+		},
+		{
+			Name:   "starts_with",
+			Doc:    "Reports whether the string begins with the prefix.",
+			Params: []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				str := args[0].(*object.String).Value
+				return nativeBoolToBooleanObject(strings.HasPrefix(str, args[1].(*object.String).Value))
+			},
+		},
+		{
+			Name:   "ends_with",
+			Doc:    "Reports whether the string ends with the suffix.",
+			Params: []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				str := args[0].(*object.String).Value
+				return nativeBoolToBooleanObject(strings.HasSuffix(str, args[1].(*object.String).Value))
+			},
+		},
+		{
+			Name:   "index_of",
+			Doc:    "Returns the index of the first occurrence of a substring in a string or an element in an array, -1 if there is none.",
+			Params: []object.ObjectType{object.ANY_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				switch arg := args[0].(type) {
+
+				case *object.String:
+					sub, ok := args[1].(*object.String)
+					if !ok {
+						return newError("argument 2 to `index_of` should be STRING, got %s", args[1].Type())
+					}
+
+					idx := strings.Index(arg.Value, sub.Value)
+					if idx >= 0 {
+						idx = utf8.RuneCountInString(arg.Value[:idx])
+					}
+					return &object.Integer{Value: int64(idx)}
+
+				case *object.Array:
+					for i, element := range arg.Elements {
+						if object.Equal(element, args[1]) {
+							return &object.Integer{Value: int64(i)}
+						}
+					}
+					return &object.Integer{Value: -1}
+
+				default:
+					return newError("argument to `index_of` not supported, got %s", arg.Type())
+				}
+			},
+		},
+		{
+			Name:   "chars",
+			Doc:    "Returns an array of runes of the string.",
+			Params: []object.ObjectType{object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				str := args[0].(*object.String).Value
+
+				chars := []string{}
+				for _, r := range str {
+					chars = append(chars, string(r))
+				}
+
+				return allocate(runtime, stringArray(chars))
+			},
+		},
+		{
+			Name:   "repeat",
+			Doc:    "Returns the string repeated n times, the same as `str * n`.",
+			Params: []object.ObjectType{object.STRING_OBJ, object.INTEGER_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return evalStringRepetition(runtime, args[0], args[1])
+			},
+		},
+		{
+			Name:     "pad",
+			Doc:      "Pads the string to the width with the fill rune (space by default): on the left for a positive width, on the right for a negative one.",
+			Params:   []object.ObjectType{object.STRING_OBJ, object.INTEGER_OBJ, object.STRING_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 3 {
+					return newError("`pad` accepts at most 3 arguments, got %d", len(args))
+				}
+
+				str := args[0].(*object.String).Value
+				width := args[1].(*object.Integer).Value
+
+				fill := " "
+				if len(args) == 3 {
+					fill = args[2].(*object.String).Value
+					if utf8.RuneCountInString(fill) != 1 {
+						return newError("`pad` fill should be a single rune, got %q", fill)
+					}
+				}
+
+				left := width > 0
+				if width < 0 {
+					if width == math.MinInt64 {
+						return newError("padded string is too long")
+					}
+					width = -width
+				}
+
+				missing := width - int64(utf8.RuneCountInString(str))
+				if missing <= 0 {
+					return args[0]
+				}
+
+				if missing > math.MaxInt32/int64(len(fill)) {
+					return newError("padded string is too long")
+				}
+
+				if err := runtime.AllocateBytes(int64(len(str)) + missing*int64(len(fill))); err != nil {
+					return err
+				}
+
+				padding := strings.Repeat(fill, int(missing))
+				if left {
+					return &object.String{Value: padding + str}
+				}
+				return &object.String{Value: str + padding}
+			},
+		},
+		{
+			Name:     "format",
+			Doc:      "Replaces `{}` placeholders with arguments, e.g. `{0:>5}` or `{:.2}`: optional index, alignment (<, > or ^), zero padding, width and precision.",
+			Params:   []object.ObjectType{object.STRING_OBJ, object.ANY_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return formatString(runtime, args[0].(*object.String).Value, args[1:])
+			},
+		},
+		{
+			Name:   "str",
+			Doc:    "Converts the argument to a string.",
+			Params: []object.ObjectType{object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if str, ok := args[0].(*object.String); ok {
+					return str
+				}
+
+				return allocate(runtime, &object.String{Value: args[0].Inspect()})
+			},
+		},
+		{
+			Name:   "int",
+			Doc:    "Converts a string or an integer to an integer, returns an error if the string is not a number.",
+			Params: []object.ObjectType{object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				switch arg := args[0].(type) {
+
+				case *object.Integer:
+					return arg
+
+				case *object.String:
+					value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+					if err != nil {
+						return newError("cannot parse %q as INTEGER", arg.Value)
+					}
+					return &object.Integer{Value: value}
+
+				default:
+					return newError("argument to `int` not supported, got %s", arg.Type())
+				}
+			},
+		},
+	}
+}
+
+func stringArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, value := range values {
+		elements[i] = &object.String{Value: value}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// formatString implements `format`, placeholders are `{[index][:spec]}`, see
+// formatValue for the spec, `{{` and `}}` are literal braces
+func formatString(runtime *object.Runtime, template string, args []object.Object) object.Object {
+	var out strings.Builder
+	next := 0
+
+	for i := 0; i < len(template); i++ {
+		ch := template[i]
+
+		if ch == '}' {
+			if i+1 < len(template) && template[i+1] == '}' {
+				out.WriteByte('}')
+				i++
+				continue
+			}
+			return newError("single `}` in format string")
+		}
+
+		if ch != '{' {
+			out.WriteByte(ch)
+			continue
+		}
+
+		if i+1 < len(template) && template[i+1] == '{' {
+			out.WriteByte('{')
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(template[i:], '}')
+		if end < 0 {
+			return newError("unclosed placeholder in format string")
+		}
+
+		placeholder := template[i+1 : i+end]
+		i += end
+
+		index, spec, _ := strings.Cut(placeholder, ":")
+		position := next
+		if index != "" {
+			parsed, err := strconv.Atoi(index)
+			if err != nil {
+				return newError("invalid placeholder in format string: {%s}", placeholder)
+			}
+			position = parsed
+		} else {
+			next++
+		}
+
+		if position < 0 || position >= len(args) {
+			return newError("format string refers to argument %d, got %d arguments", position, len(args))
+		}
+
+		formatted := formatValue(runtime, args[position], spec)
+		if isError(formatted) {
+			return formatted
+		}
+		out.WriteString(formatted.(*object.String).Value)
+	}
+
+	return allocate(runtime, &object.String{Value: out.String()})
+}
+
+// formatValue formats a single value according to the `[align][0][width][.precision]`
+// spec: precision is the minimum number of digits of integers and the maximum
+// number of runes of other values, integers are aligned right by default and
+// other values left.
+func formatValue(runtime *object.Runtime, value object.Object, spec string) object.Object {
+	original := spec
+
+	align := byte(0)
+	if spec != "" && strings.IndexByte("<>^", spec[0]) >= 0 {
+		align, spec = spec[0], spec[1:]
+	}
+
+	zero := strings.HasPrefix(spec, "0")
+	spec = strings.TrimPrefix(spec, "0")
+
+	widthSpec, precisionSpec, hasPrecision := strings.Cut(spec, ".")
+
+	width := 0
+	if widthSpec != "" {
+		parsed, err := strconv.ParseUint(widthSpec, 10, 31)
+		if err != nil {
+			return newError("invalid format spec: %q", original)
+		}
+		width = int(parsed)
+	}
+
+	precision := 0
+	if hasPrecision {
+		parsed, err := strconv.ParseUint(precisionSpec, 10, 31)
+		if err != nil {
+			return newError("invalid format spec: %q", original)
+		}
+		precision = int(parsed)
+	}
+
+	integer, isInteger := value.(*object.Integer)
+
+	text := value.Inspect()
+	sign := ""
+	switch {
+	case isInteger && hasPrecision:
+		if integer.Value < 0 {
+			sign, text = "-", strings.TrimPrefix(text, "-")
+		}
+		if missing := precision - len(text); missing > 0 {
+			if err := runtime.AllocateBytes(int64(missing)); err != nil {
+				return err
+			}
+			text = strings.Repeat("0", missing) + text
+		}
+	case hasPrecision:
+		if runes := []rune(text); len(runes) > precision {
+			text = string(runes[:precision])
+		}
+	}
+
+	missing := width - len(sign) - utf8.RuneCountInString(text)
+	if missing <= 0 {
+		return &object.String{Value: sign + text}
+	}
+
+	if err := runtime.AllocateBytes(int64(missing)); err != nil {
+		return err
+	}
+
+	if align == 0 {
+		align = '<'
+		if isInteger {
+			align = '>'
+		}
+	}
+
+	switch {
+	case zero && isInteger && align == '>':
+		if integer.Value < 0 && sign == "" {
+			sign, text = "-", strings.TrimPrefix(text, "-")
+		}
+		return &object.String{Value: sign + strings.Repeat("0", missing) + text}
+	case align == '>':
+		return &object.String{Value: strings.Repeat(" ", missing) + sign + text}
+	case align == '^':
+		left := missing / 2
+		return &object.String{Value: strings.Repeat(" ", left) + sign + text + strings.Repeat(" ", missing-left)}
+	default:
+		return &object.String{Value: sign + text + strings.Repeat(" ", missing)}
+	}
+}
@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"time"
+)
+
+// timeBuiltins use layouts with Go's reference time, see time.Layout, durations
+// are integer seconds or strings like "1h30m".
+func timeBuiltins() []*object.Builtin {
+	return []*object.Builtin{
+		{
+			Name:   "now",
+			Doc:    "Returns the current time.",
+			Params: []object.ObjectType{},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return &object.Time{Value: runtime.Now()}
+			},
+		},
+		{
+			Name:     "parse_time",
+			Doc:      "Parses a time in RFC 3339 format or in the given layout, e.g. \"2006-01-02 15:04\".",
+			Params:   []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 2 {
+					return newError("`parse_time` accepts at most 2 arguments, got %d", len(args))
+				}
+
+				layout := time.RFC3339Nano
+				if len(args) == 2 {
+					layout = args[1].(*object.String).Value
+				}
+
+				parsed, err := time.Parse(layout, args[0].(*object.String).Value)
+				if err != nil {
+					return newError("cannot parse time: %s", err)
+				}
+
+				return &object.Time{Value: parsed}
+			},
+		},
+	}
+}
+
+// timeMethods take the time as the receiver, e.g. `now().format("15:04")`
+func timeMethods() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"format": {
+			Name:     "format",
+			Doc:      "Formats the time in RFC 3339 format or in the given layout.",
+			Params:   []object.ObjectType{object.TIME_OBJ, object.STRING_OBJ},
+			Variadic: true,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				if len(args) > 2 {
+					return newError("`format` accepts at most 2 arguments, got %d", len(args))
+				}
+
+				layout := time.RFC3339Nano
+				if len(args) == 2 {
+					layout = args[1].(*object.String).Value
+				}
+
+				return allocate(runtime, &object.String{Value: args[0].(*object.Time).Value.Format(layout)})
+			},
+		},
+		"add": {
+			Name:   "add",
+			Doc:    "Returns the time moved by a duration, negative durations move it back.",
+			Params: []object.ObjectType{object.TIME_OBJ, object.ANY_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				var duration time.Duration
+
+				switch arg := args[1].(type) {
+
+				case *object.Integer:
+					if arg.Value > maxDurationSeconds || arg.Value < -maxDurationSeconds {
+						return newError("duration is too long: %d seconds", arg.Value)
+					}
+					duration = time.Duration(arg.Value) * time.Second
+
+				case *object.String:
+					parsed, err := time.ParseDuration(arg.Value)
+					if err != nil {
+						return newError("invalid duration: %s", err)
+					}
+					duration = parsed
+
+				default:
+					return newError("argument 2 to `add` should be INTEGER or STRING, got %s", arg.Type())
+				}
+
+				return &object.Time{Value: args[0].(*object.Time).Value.Add(duration)}
+			},
+		},
+		"diff": {
+			Name:   "diff",
+			Doc:    "Returns the number of whole seconds from the other time to this one.",
+			Params: []object.ObjectType{object.TIME_OBJ, object.TIME_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				elapsed := args[0].(*object.Time).Value.Sub(args[1].(*object.Time).Value)
+				return &object.Integer{Value: int64(elapsed / time.Second)}
+			},
+		},
+		"in_zone": {
+			Name:   "in_zone",
+			Doc:    "Returns the same instant in a time zone, e.g. \"UTC\", \"Local\" or \"Europe/Berlin\".",
+			Params: []object.ObjectType{object.TIME_OBJ, object.STRING_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				name := args[1].(*object.String).Value
+
+				location, err := time.LoadLocation(name)
+				if err != nil {
+					return newError("unknown time zone %q", name)
+				}
+
+				return &object.Time{Value: args[0].(*object.Time).Value.In(location)}
+			},
+		},
+		"unix": {
+			Name:   "unix",
+			Doc:    "Returns the number of seconds since January 1, 1970 UTC.",
+			Params: []object.ObjectType{object.TIME_OBJ},
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return &object.Integer{Value: args[0].(*object.Time).Value.Unix()}
+			},
+		},
+	}
+}
+
+// maxDurationSeconds is the longest time.Duration in whole seconds
+const maxDurationSeconds = int64(1<<63-1) / int64(time.Second)
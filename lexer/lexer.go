@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"github.com/lancelote/writing-an-interpreter-in-go/token"
+	"strings"
 )
 
 type Lexer struct {
@@ -17,6 +18,19 @@ func New(input string) *Lexer {
 	return l
 }
 
+// StripShebang removes the `#!` line of an executable script, it's only
+// allowed at the start of a whole script
+func StripShebang(source string) string {
+	if !strings.HasPrefix(source, "#!") {
+		return source
+	}
+
+	if i := strings.IndexByte(source, '\n'); i >= 0 {
+		return source[i:]
+	}
+	return ""
+}
+
 func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
@@ -75,26 +89,62 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = token.NewToken(token.BANG, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = token.NewToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = token.NewToken(token.ILLEGAL, l.ch)
+		}
 	case '/':
 		tok = token.NewToken(token.SLASH, l.ch)
 	case '*':
 		tok = token.NewToken(token.ASTERISK, l.ch)
 	case '<':
-		tok = token.NewToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: "<="}
+		} else {
+			tok = token.NewToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = token.NewToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: ">="}
+		} else {
+			tok = token.NewToken(token.GT, l.ch)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
 	case '"':
-		tok.Literal = l.readString()
+		literal, interpolated := l.readString()
+		tok.Literal = literal
 		tok.Type = token.STRING
+		if interpolated {
+			tok.Type = token.TEMPLATE
+		}
 	case '[':
 		tok = token.NewToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = token.NewToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = token.NewToken(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = token.NewToken(token.DOT, l.ch)
+		}
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
@@ -135,15 +185,86 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[startPosition:l.position]
 }
 
-func (l *Lexer) readString() string {
+// readString reads a string literal and reports whether it has `${expr}`
+// interpolations, they may contain nested strings and braces
+func (l *Lexer) readString() (string, bool) {
 	position := l.position + 1
+	interpolated := false
 	for {
 		l.readChar()
+		if l.ch == '$' && l.peekChar() == '{' {
+			interpolated = true
+			l.readChar()
+			l.skipInterpolation()
+		}
 		if l.ch == '"' || l.ch == 0 {
 			break
 		}
 	}
-	return l.input[position:l.position]
+	return l.input[position:l.position], interpolated
+}
+
+// skipInterpolation moves from the opening brace of an interpolation to the
+// matching closing one
+func (l *Lexer) skipInterpolation() {
+	depth := 1
+	for depth > 0 {
+		l.readChar()
+		switch l.ch {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			l.readString()
+		}
+		if l.ch == 0 {
+			return
+		}
+	}
+}
+
+// TemplatePart is a piece of a TEMPLATE literal: either text or source of an
+// interpolated expression.
+type TemplatePart struct {
+	Value        string
+	IsExpression bool
+}
+
+// SplitTemplate splits a TEMPLATE literal into text and expression parts, an
+// unterminated interpolation is reported as false.
+func SplitTemplate(literal string) ([]TemplatePart, bool) {
+	l := New(literal)
+	parts := []TemplatePart{}
+	start := 0
+
+	for l.ch != 0 {
+		if l.ch != '$' || l.peekChar() != '{' {
+			l.readChar()
+			continue
+		}
+
+		if l.position > start {
+			parts = append(parts, TemplatePart{Value: literal[start:l.position]})
+		}
+
+		l.readChar()
+		source := l.position + 1
+		l.skipInterpolation()
+		if l.ch == 0 {
+			return nil, false
+		}
+
+		parts = append(parts, TemplatePart{Value: literal[source:l.position], IsExpression: true})
+		l.readChar()
+		start = l.position
+	}
+
+	if len(literal) > start {
+		parts = append(parts, TemplatePart{Value: literal[start:]})
+	}
+
+	return parts, true
 }
 
 func isDigit(ch byte) bool {
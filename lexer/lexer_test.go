@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"github.com/lancelote/writing-an-interpreter-in-go/token"
+	"reflect"
 	"testing"
 )
 
@@ -30,6 +31,12 @@ if (5 < 10) {
 [1, 2];
 {"foo": "bar"}
 macro(x, y) { x + y; };
+db.query("x");
+a && b || c;
+"a" <= "b" >= "c" in s[1:2];
+f(...a.b);
+"a ${b + "${c}"} {d}" "{e}"
+export let m = import "lib";
 `
 
 	tests := []struct {
@@ -134,6 +141,49 @@ macro(x, y) { x + y; };
 		{token.SEMICOLON, ";"},
 		{token.RBRACE, "}"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "db"},
+		{token.DOT, "."},
+		{token.IDENT, "query"},
+		{token.LPAREN, "("},
+		{token.STRING, "x"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "a"},
+		{token.LT_EQ, "<="},
+		{token.STRING, "b"},
+		{token.GT_EQ, ">="},
+		{token.STRING, "c"},
+		{token.IN, "in"},
+		{token.IDENT, "s"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "a"},
+		{token.DOT, "."},
+		{token.IDENT, "b"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.TEMPLATE, `a ${b + "${c}"} {d}`},
+		{token.STRING, "{e}"},
+		{token.EXPORT, "export"},
+		{token.LET, "let"},
+		{token.IDENT, "m"},
+		{token.ASSIGN, "="},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -160,3 +210,66 @@ macro(x, y) { x + y; };
 		}
 	}
 }
+
+func TestStripShebang(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#!/usr/bin/env monkey\nlet x = 1;", "\nlet x = 1;"},
+		{"#!/usr/bin/env monkey", ""},
+		{"let x = 1; #!", "let x = 1; #!"},
+		{" #!/usr/bin/env monkey", " #!/usr/bin/env monkey"},
+	}
+
+	for _, tt := range tests {
+		if got := StripShebang(tt.input); got != tt.expected {
+			t.Errorf("%q: want %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestShebangIsNotSkipped(t *testing.T) {
+	if tok := New("#!").NextToken(); tok.Type != token.ILLEGAL {
+		t.Errorf("want shebang to be illegal outside of scripts, got %v", tok)
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []TemplatePart
+	}{
+		{
+			`a ${b} c`,
+			[]TemplatePart{{"a ", false}, {"b", true}, {" c", false}},
+		},
+		{
+			`${a}${b}`,
+			[]TemplatePart{{"a", true}, {"b", true}},
+		},
+		{
+			`${f("}")} ${{"a": 1}["a"]}`,
+			[]TemplatePart{{`f("}")`, true}, {" ", false}, {`{"a": 1}["a"]`, true}},
+		},
+		{
+			`$ {} $`,
+			[]TemplatePart{{"$ {} $", false}},
+		},
+	}
+
+	for _, tt := range tests {
+		parts, ok := SplitTemplate(tt.input)
+		if !ok {
+			t.Fatalf("%s: unexpected unterminated interpolation", tt.input)
+		}
+
+		if !reflect.DeepEqual(parts, tt.expected) {
+			t.Errorf("%s: want %v, got %v", tt.input, tt.expected, parts)
+		}
+	}
+
+	if _, ok := SplitTemplate("a ${b"); ok {
+		t.Errorf("want unterminated interpolation to be reported")
+	}
+}
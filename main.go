@@ -1,18 +1,20 @@
 package main
 
 import (
-	"fmt"
-	"github.com/lancelote/writing-an-interpreter-in-go/repl"
+	"github.com/lancelote/writing-an-interpreter-in-go/cli"
 	"os"
-	"os/user"
 )
 
 func main() {
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
+	streams := cli.Streams{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
 	}
-	fmt.Printf("hello %s! this is the Monkey programming language!\n", user.Username)
-	fmt.Printf("feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+
+	if info, err := os.Stdin.Stat(); err == nil {
+		streams.Interactive = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	os.Exit(cli.Main(os.Args[1:], streams))
 }
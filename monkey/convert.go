@@ -0,0 +1,692 @@
+package monkey
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/lancelote/writing-an-interpreter-in-go/evaluator"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var (
+	objectType = reflect.TypeFor[object.Object]()
+	errorType  = reflect.TypeFor[error]()
+)
+
+// ToObject converts a Go value to a Monkey object:
+//
+//   - nil and nil pointers become null
+//   - bool, integers and strings become their Monkey counterparts
+//   - slices and arrays become arrays
+//   - maps become hashes, keys have to convert to hashable objects
+//   - structs become hashes keyed by field names, a `monkey:"name"` tag
+//     renames the field and `monkey:"-"` skips it
+//   - funcs become builtins, see Func
+//   - object.Object values are returned as is, use Wrap to pass other values
+//     through Monkey code as they are
+func ToObject(value any) (object.Object, error) {
+	if value == nil {
+		return evaluator.NULL, nil
+	}
+	return toObject(reflect.ValueOf(value))
+}
+
+func toObject(v reflect.Value) (object.Object, error) {
+	if v.Type().Implements(objectType) {
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return v.Interface().(object.Object), nil
+	}
+
+	switch v.Kind() {
+
+	case reflect.Bool:
+		if v.Bool() {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("integer overflow: %d", v.Uint())
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return &object.Array{Elements: []object.Object{}}, nil
+		}
+
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			element, err := toObject(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		hash := &object.Hash{}
+
+		// Go maps are unordered, keys are sorted to keep hashes deterministic
+		keys := v.MapKeys()
+		slices.SortFunc(keys, compareKeys)
+
+		for _, k := range keys {
+			key, err := toObject(k)
+			if err != nil {
+				return nil, err
+			}
+
+			if !object.CanHash(key) {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+
+			value, err := toObject(v.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+
+			hash.Set(key.(object.Hashable), value)
+		}
+		return hash, nil
+
+	case reflect.Struct:
+		hash := &object.Hash{}
+
+		for _, field := range reflect.VisibleFields(v.Type()) {
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+
+			fieldValue, err := v.FieldByIndexErr(field.Index)
+			if err != nil {
+				continue // promoted through a nil embedded pointer
+			}
+
+			value, err := toObject(fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+
+			hash.Set(&object.String{Value: name}, value)
+		}
+		return hash, nil
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return toObject(v.Elem())
+
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return Func("", v.Interface())
+
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", v.Type())
+	}
+}
+
+// FromObject stores the Monkey object in the value pointed to by target,
+// the conversion rules mirror ToObject. Storing into `any` produces int64,
+// string, bool, nil, []any and map[string]any (map[any]any if some hash key
+// is not a string). Host objects are stored if their value fits the
+// target. Monkey functions and builtins can be stored in a func
+// variable, errors raised by the call are returned through a trailing error
+// result if the func has one, otherwise the call panics with *RuntimeError.
+func FromObject(obj object.Object, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("target must be a non-nil pointer")
+	}
+
+	converted, err := fromObject(nil, obj, v.Type().Elem())
+	if err != nil {
+		return err
+	}
+
+	v.Elem().Set(converted)
+	return nil
+}
+
+// fromObject converts the object to a value of type t, Monkey functions
+// stored in funcs are called on the runtime, on the one of their environment
+// if it's nil
+func fromObject(runtime *object.Runtime, obj object.Object, t reflect.Type) (reflect.Value, error) {
+	switch {
+	case t == objectType:
+		return reflect.ValueOf(&obj).Elem(), nil
+
+	case t.Implements(objectType):
+		// a concrete object type or an interface extending object.Object
+		if !reflect.TypeOf(obj).AssignableTo(t) {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+		return reflect.ValueOf(obj), nil
+	}
+
+	if host, ok := obj.(*object.Host); ok && host.Value != nil {
+		if value := reflect.ValueOf(host.Value); value.Type().AssignableTo(t) {
+			return value, nil
+		}
+	}
+
+	if _, ok := obj.(*object.Null); ok {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+	}
+
+	switch t.Kind() {
+
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+		native, err := toNative(obj)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if native == nil {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(native), nil
+
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+		return reflect.ValueOf(b.Value).Convert(t), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+		v := reflect.New(t).Elem()
+		if v.OverflowInt(i.Value) {
+			return reflect.Value{}, fmt.Errorf("integer %d overflows %s", i.Value, t)
+		}
+		v.SetInt(i.Value)
+		return v, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+		v := reflect.New(t).Elem()
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return reflect.Value{}, fmt.Errorf("integer %d overflows %s", i.Value, t)
+		}
+		v.SetUint(uint64(i.Value))
+		return v, nil
+
+	case reflect.String:
+		s, ok := obj.(*object.String)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+		return reflect.ValueOf(s.Value).Convert(t), nil
+
+	case reflect.Slice:
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+		v := reflect.MakeSlice(t, len(arr.Elements), len(arr.Elements))
+		for i, element := range arr.Elements {
+			converted, err := fromObject(runtime, element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(converted)
+		}
+		return v, nil
+
+	case reflect.Array:
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+		if len(arr.Elements) != t.Len() {
+			return reflect.Value{}, fmt.Errorf("want array of length %d, got %d", t.Len(), len(arr.Elements))
+		}
+
+		v := reflect.New(t).Elem()
+		for i, element := range arr.Elements {
+			converted, err := fromObject(runtime, element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(converted)
+		}
+		return v, nil
+
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+		v := reflect.MakeMapWithSize(t, hash.Len())
+		for _, pair := range hash.Pairs() {
+			key, err := fromObject(runtime, pair.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			if err := checkMapKey(key, pair.Key); err != nil {
+				return reflect.Value{}, err
+			}
+
+			value, err := fromObject(runtime, pair.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+
+			v.SetMapIndex(key, value)
+		}
+		return v, nil
+
+	case reflect.Struct:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+		v := reflect.New(t).Elem()
+		for _, field := range reflect.VisibleFields(t) {
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+
+			value, ok := hash.Get(&object.String{Value: name})
+			if !ok {
+				continue
+			}
+
+			fieldValue, err := v.FieldByIndexErr(field.Index)
+			if err != nil {
+				continue // promoted through a nil embedded pointer
+			}
+
+			converted, err := fromObject(runtime, value, field.Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			fieldValue.Set(converted)
+		}
+		return v, nil
+
+	case reflect.Pointer:
+		converted, err := fromObject(runtime, obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		v := reflect.New(t.Elem())
+		v.Elem().Set(converted)
+		return v, nil
+
+	case reflect.Func:
+		switch obj.(type) {
+		case *object.Function, *object.Builtin:
+			return makeFunc(runtime, obj, t), nil
+		default:
+			return reflect.Value{}, mismatch(obj, t)
+		}
+
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type: %s", t)
+	}
+}
+
+func toNative(obj object.Object) (any, error) {
+	switch obj := obj.(type) {
+
+	case *object.Null:
+		return nil, nil
+
+	case *object.Boolean:
+		return obj.Value, nil
+
+	case *object.Integer:
+		return obj.Value, nil
+
+	case *object.String:
+		return obj.Value, nil
+
+	case *object.Array:
+		elements := make([]any, len(obj.Elements))
+		for i, element := range obj.Elements {
+			native, err := toNative(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = native
+		}
+		return elements, nil
+
+	case *object.Hash:
+		strKeys := make(map[string]any, obj.Len())
+		anyKeys := make(map[any]any, obj.Len())
+
+		for _, pair := range obj.Pairs() {
+			key, err := toNative(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := toNative(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := checkMapKey(reflect.ValueOf(key), pair.Key); err != nil {
+				return nil, err
+			}
+
+			if s, ok := key.(string); ok {
+				strKeys[s] = value
+			}
+			anyKeys[key] = value
+		}
+
+		if len(strKeys) == len(anyKeys) {
+			return strKeys, nil
+		}
+		return anyKeys, nil
+
+	default:
+		return obj, nil
+	}
+}
+
+// Func wraps a Go function into a builtin, arguments and results are
+// converted with FromObject and ToObject. The function may return a single
+// value, an error, or a value and an error; a non-nil error is turned into
+// a Monkey error object. Monkey functions passed for func parameters run on
+// the runtime of the caller and their errors become errors of the builtin.
+func Func(name string, fn any) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("want function, got %T", fn)
+	}
+
+	t := v.Type()
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("function %s returns too many values", name)
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("second result of function %s should be error", name)
+	}
+
+	params := make([]object.ObjectType, t.NumIn())
+	for i := range params {
+		in := t.In(i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			in = in.Elem()
+		}
+		params[i] = paramType(in)
+	}
+
+	builtin := &object.Builtin{Name: name, Params: params, Variadic: t.IsVariadic()}
+	builtin.Fn = func(runtime *object.Runtime, args ...object.Object) (result object.Object) {
+		// Monkey functions passed as arguments panic if they fail, see makeFunc
+		defer func() {
+			if r := recover(); r != nil {
+				callbackErr, ok := r.(*RuntimeError)
+				if !ok {
+					panic(r)
+				}
+				result = callbackErr.Object
+			}
+		}()
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			paramType := t.In(min(i, t.NumIn()-1))
+			if t.IsVariadic() && i >= t.NumIn()-1 {
+				paramType = paramType.Elem()
+			}
+
+			converted, err := fromObject(runtime, arg, paramType)
+			if err != nil {
+				return &object.Error{Message: fmt.Sprintf("argument %d to `%s`: %s", i+1, name, err)}
+			}
+			in[i] = converted
+		}
+
+		out := v.Call(in)
+
+		if len(out) > 0 && out[len(out)-1].Type() == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				// errors of Monkey callbacks keep their cause, e.g. `exit`
+				var runtimeErr *RuntimeError
+				if errors.As(err, &runtimeErr) && runtimeErr.Object.Cause != nil {
+					return runtimeErr.Object
+				}
+				return &object.Error{Message: err.Error()}
+			}
+			out = out[:len(out)-1]
+		}
+
+		if len(out) == 0 {
+			return evaluator.NULL
+		}
+
+		converted, err := toObject(out[0])
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("result of `%s`: %s", name, err)}
+		}
+		return converted
+	}
+
+	return builtin, nil
+}
+
+// RegisterFunc exposes a Go function to scripts under the given name, see
+// Func for the conversion rules.
+func (i *Interpreter) RegisterFunc(name string, fn any) error {
+	builtin, err := Func(name, fn)
+	if err != nil {
+		return err
+	}
+
+	i.Register(builtin)
+	return nil
+}
+
+// Wrap makes a host object out of the Go value, exported methods of the value
+// are callable from Monkey code, e.g. `db.Query("...")`. Arguments and results
+// of methods are converted as in Func, methods Func can't wrap are skipped.
+func Wrap(typeName string, value any) *object.Host {
+	v := reflect.ValueOf(value)
+	methods := make(map[string]*object.Builtin)
+
+	if !v.IsValid() {
+		return &object.Host{TypeName: typeName, Methods: methods}
+	}
+
+	for i := range v.NumMethod() {
+		name := v.Type().Method(i).Name
+
+		method, err := Func(name, v.Method(i).Interface())
+		if err != nil {
+			continue
+		}
+
+		methods[name] = &object.Builtin{
+			Name:     method.Name,
+			Params:   append([]object.ObjectType{object.HOST_OBJ}, method.Params...),
+			Variadic: method.Variadic,
+			Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+				return method.Fn(runtime, args[1:]...) // receiver is already bound
+			},
+		}
+	}
+
+	return &object.Host{TypeName: typeName, Value: value, Methods: methods}
+}
+
+// makeFunc makes a Go func calling the Monkey function on the runtime. Errors
+// of the call are returned through a trailing error result if the func has
+// one, otherwise the func panics with *RuntimeError, Func recovers it.
+func makeFunc(runtime *object.Runtime, fn object.Object, t reflect.Type) reflect.Value {
+	if runtime == nil {
+		runtime = object.NewRuntime()
+		if function, ok := fn.(*object.Function); ok {
+			runtime = function.Env.Runtime()
+		}
+	}
+
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		args := make([]object.Object, 0, len(in))
+		for i, arg := range in {
+			if t.IsVariadic() && i == len(in)-1 {
+				for j := range arg.Len() {
+					args = append(args, mustToObject(arg.Index(j)))
+				}
+				continue
+			}
+			args = append(args, mustToObject(arg))
+		}
+
+		result := evaluator.Apply(runtime, fn, args)
+
+		out := make([]reflect.Value, t.NumOut())
+		var err error
+
+		if errObj, ok := result.(*object.Error); ok {
+			err = &RuntimeError{Object: errObj}
+		}
+
+		for i := range out {
+			switch {
+			case t.Out(i) == errorType:
+				if err != nil {
+					out[i] = reflect.ValueOf(&err).Elem()
+				} else {
+					out[i] = reflect.Zero(errorType)
+				}
+			case err != nil || result == nil:
+				out[i] = reflect.Zero(t.Out(i))
+			default:
+				converted, convErr := fromObject(runtime, result, t.Out(i))
+				if convErr != nil {
+					err = callbackError("result of Monkey function: %s", convErr)
+					converted = reflect.Zero(t.Out(i))
+				}
+				out[i] = converted
+			}
+		}
+
+		if err != nil && (t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType) {
+			panic(err)
+		}
+
+		return out
+	})
+}
+
+func mustToObject(v reflect.Value) object.Object {
+	obj, err := toObject(v)
+	if err != nil {
+		panic(callbackError("argument of Monkey function: %s", err))
+	}
+	return obj
+}
+
+func callbackError(format string, a ...any) *RuntimeError {
+	return &RuntimeError{Object: &object.Error{Message: fmt.Sprintf(format, a...)}}
+}
+
+func paramType(t reflect.Type) object.ObjectType {
+	if t.Implements(objectType) {
+		return object.ANY_OBJ
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return object.BOOLEAN_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object.INTEGER_OBJ
+	case reflect.String:
+		return object.STRING_OBJ
+	case reflect.Slice, reflect.Array:
+		return object.ARRAY_OBJ
+	case reflect.Map, reflect.Struct:
+		return object.HASH_OBJ
+	default:
+		return object.ANY_OBJ
+	}
+}
+
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() || field.Anonymous {
+		return "", false
+	}
+
+	tag := field.Tag.Get("monkey")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+func compareKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	default:
+		return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+}
+
+// checkMapKey rejects keys Go maps can't hold: arrays are valid Monkey keys,
+// but slices are not comparable
+func checkMapKey(key reflect.Value, obj object.Object) error {
+	if !key.Comparable() {
+		return fmt.Errorf("unusable as Go map key: %s", obj.Type())
+	}
+	return nil
+}
+
+func mismatch(obj object.Object, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+}
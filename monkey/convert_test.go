@@ -0,0 +1,362 @@
+package monkey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type user struct {
+	Name    string `monkey:"name"`
+	Age     int    `monkey:"age"`
+	Admin   bool
+	Tags    []string `monkey:"tags"`
+	Secret  string   `monkey:"-"`
+	private int
+}
+
+func TestToObject(t *testing.T) {
+	var nilPointer *user
+
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{nil, "null"},
+		{nilPointer, "null"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{"hello", "hello"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{[]int(nil), "[]"},
+		{map[string]int{"one": 1}, "{one: 1}"},
+		{map[string]int{"b": 2, "c": 3, "a": 1}, "{a: 1, b: 2, c: 3}"},
+		{map[int]bool{10: true, 9: false}, "{9: false, 10: true}"},
+		{&object.Integer{Value: 5}, "5"},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.value)
+		if err != nil {
+			t.Errorf("%#v: unexpected error: %s", tt.value, err)
+			continue
+		}
+
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%#v: want %q, got %q", tt.value, tt.expected, obj.Inspect())
+		}
+	}
+
+	if _, err := ToObject(1.5); err == nil {
+		t.Errorf("want error converting float")
+	}
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	interpreter := New()
+
+	obj, err := ToObject(user{Name: "monkey", Age: 3, Admin: true, Tags: []string{"a"}, Secret: "x", private: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	interpreter.Set("u", obj)
+
+	evaluated, err := interpreter.Run(`
+		if (u["Admin"] == true) {
+			{"name": u["name"] + "!", "age": u["age"] + 1, "tags": push(u["tags"], "b"), "Secret": u["Secret"]}
+		}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var got user
+	if err := FromObject(evaluated, &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := user{Name: "monkey!", Age: 4, Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("want %+v, got %+v", expected, got)
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	interpreter := New()
+
+	evaluated, err := interpreter.Run(`{"list": [1, "two", true, if (false) { 1 }], "nested": {"x": 1}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var native any
+	if err := FromObject(evaluated, &native); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]any{
+		"list":   []any{int64(1), "two", true, nil},
+		"nested": map[string]any{"x": int64(1)},
+	}
+	if !reflect.DeepEqual(native, expected) {
+		t.Errorf("want %#v, got %#v", expected, native)
+	}
+
+	var numbers map[string]int8
+	if err := FromObject(&object.Hash{}, &numbers); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	var small int8
+	if err := FromObject(&object.Integer{Value: 1000}, &small); err == nil {
+		t.Errorf("want overflow error")
+	}
+
+	var str string
+	if err := FromObject(&object.Integer{Value: 1}, &str); err == nil {
+		t.Errorf("want type mismatch error")
+	}
+
+	if err := FromObject(&object.Integer{Value: 1}, str); err == nil {
+		t.Errorf("want error on non-pointer target")
+	}
+
+	arrayKey, err := interpreter.Run(`{[1, 2]: 3}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var anyMap map[any]any
+	if err := FromObject(arrayKey, &anyMap); err == nil || err.Error() != "unusable as Go map key: ARRAY" {
+		t.Errorf("want error converting array key, got %v", err)
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	interpreter := New()
+
+	err := interpreter.RegisterFunc("split_n", func(s string, n int) ([]string, error) {
+		if n < 0 {
+			return nil, errors.New("negative count")
+		}
+		return strings.SplitN(s, ",", n), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = interpreter.RegisterFunc("sum", func(numbers ...int) int {
+		total := 0
+		for _, n := range numbers {
+			total += n
+		}
+		return total
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = interpreter.RegisterFunc("show", func(v any) string {
+		return fmt.Sprint(v)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`split_n("a,b,c", 2)`, "[a, b,c]"},
+		{`show({"a": 1})`, "map[a:1]"},
+		{`show({[1, 2]: 3})`, "ERROR: argument 1 to `show`: unusable as Go map key: ARRAY"},
+		{`split_n("a,b,c", -1 * 1)`, "ERROR: negative count"},
+		{`split_n(1, 2)`, "ERROR: argument 1 to `split_n` should be STRING, got INTEGER"},
+		{`sum()`, "0"},
+		{`sum(1, 2, 3)`, "6"},
+		{`help(split_n)`, "split_n(STRING, INTEGER)"},
+	}
+
+	for _, tt := range tests {
+		evaluated, err := interpreter.Run(tt.input)
+
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			evaluated = runtimeErr.Object
+		} else if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	if err := interpreter.RegisterFunc("bad", 5); err == nil {
+		t.Errorf("want error registering non-function")
+	}
+
+	if err := interpreter.RegisterFunc("bad", func() (int, int) { return 0, 0 }); err == nil {
+		t.Errorf("want error registering function without error result")
+	}
+}
+
+func TestMonkeyFunctionToGo(t *testing.T) {
+	interpreter := New()
+
+	evaluated, err := interpreter.Run(`fn(x, y) { if (y == 0) { x / "zero" } else { x / y } }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var divide func(int, int) (int, error)
+	if err := FromObject(evaluated, &divide); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := divide(10, 2)
+	if err != nil || result != 5 {
+		t.Errorf("want 5, got %d (%v)", result, err)
+	}
+
+	if _, err := divide(1, 0); err == nil {
+		t.Errorf("want error from Monkey function")
+	}
+}
+
+func TestMonkeyFunctionErrorsInRegisteredFunc(t *testing.T) {
+	var stdout bytes.Buffer
+
+	interpreter := New(WithStepLimit(1000), WithStdout(&stdout))
+
+	err := interpreter.RegisterFunc("apply", func(f func(int) int, x int) int {
+		return f(x)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = interpreter.RegisterFunc("try_apply", func(f func(int) (int, error), x int) (int, error) {
+		return f(x)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		cause    error
+	}{
+		{`apply(fn(x) { x * 2 }, 2)`, "4", nil},
+		{`apply(fn(x) { x + "a" }, 2)`, "ERROR: type mismatch: INTEGER + STRING", nil},
+		{`try_apply(fn(x) { x + "a" }, 2)`, "ERROR: type mismatch: INTEGER + STRING", nil},
+		{`apply(fn(x) { "s" }, 2)`, "ERROR: result of Monkey function: cannot convert STRING to int", nil},
+		{`apply(fn(x) { exit(3) }, 2)`, "ERROR: evaluation aborted: exit status 3", &object.ExitError{Code: 3}},
+		{`try_apply(fn(x) { exit(3) }, 2)`, "ERROR: evaluation aborted: exit status 3", &object.ExitError{Code: 3}},
+		{`let loop = fn(x) { loop(x) }; apply(loop, 2)`, "ERROR: evaluation aborted: step limit exceeded", object.ErrStepLimit},
+		{`apply(fn(x) { puts(x); x }, 7)`, "7", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated, err := interpreter.Run(tt.input)
+
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			evaluated = runtimeErr.Object
+		} else if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+
+		var exitErr *object.ExitError
+		switch {
+		case tt.cause == nil && err != nil && errors.Unwrap(err) != nil:
+			t.Errorf("%s: unexpected cause %v", tt.input, errors.Unwrap(err))
+		case errors.As(tt.cause, &exitErr):
+			var got *object.ExitError
+			if !errors.As(err, &got) || got.Code != exitErr.Code {
+				t.Errorf("%s: want exit code %d, got %v", tt.input, exitErr.Code, err)
+			}
+		case tt.cause != nil && !errors.Is(err, tt.cause):
+			t.Errorf("%s: want cause %v, got %v", tt.input, tt.cause, err)
+		}
+	}
+
+	// callbacks share the runtime of the caller, including its stdout
+	if stdout.String() != "7\n" {
+		t.Errorf("want callback output in the interpreter stdout, got %q", stdout.String())
+	}
+}
+
+type store struct {
+	items map[string]int
+}
+
+func (s *store) Put(key string, value int) {
+	s.items[key] = value
+}
+
+func (s *store) Get(key string) (int, error) {
+	value, ok := s.items[key]
+	if !ok {
+		return 0, errors.New("missing key " + key)
+	}
+	return value, nil
+}
+
+func (s *store) Keys() []string {
+	keys := []string{}
+	for key := range s.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestWrap(t *testing.T) {
+	s := &store{items: map[string]int{}}
+
+	interpreter := New()
+	interpreter.Set("store", Wrap("Store", s))
+
+	evaluated, err := interpreter.Run(`store.Put("a", 1); store.Put("b", store.Get("a") + 1); store.Get("b")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, evaluated, 2)
+
+	if s.items["b"] != 2 {
+		t.Errorf("host value is not modified")
+	}
+
+	if _, err := interpreter.Run(`store.Get("c")`); err == nil || err.Error() != "missing key c" {
+		t.Errorf("want missing key error, got %v", err)
+	}
+
+	if _, err := interpreter.Run(`store.Put("c")`); err == nil {
+		t.Errorf("want error on wrong number of arguments")
+	}
+
+	evaluated, err = interpreter.Run("store")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var unwrapped *store
+	if err := FromObject(evaluated, &unwrapped); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if unwrapped != s {
+		t.Errorf("want original host value, got %v", unwrapped)
+	}
+}
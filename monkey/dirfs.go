@@ -0,0 +1,42 @@
+package monkey
+
+import (
+	"io/fs"
+	"os"
+)
+
+// DirFS is a writable file system confined to a directory, scripts can't
+// reach outside of it even through symbolic links.
+type DirFS struct {
+	fs.FS
+	root *os.Root
+}
+
+// OpenDir opens the directory as a file system for WithFS, it should be
+// closed once scripts are done with it.
+func OpenDir(dir string) (*DirFS, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirFS{FS: root.FS(), root: root}, nil
+}
+
+func (d *DirFS) WriteFile(name string, data []byte) error {
+	file, err := d.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (d *DirFS) Close() error {
+	return d.root.Close()
+}
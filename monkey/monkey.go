@@ -0,0 +1,216 @@
+// Package monkey embeds the Monkey interpreter into Go programs.
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"github.com/lancelote/writing-an-interpreter-in-go/evaluator"
+	"github.com/lancelote/writing-an-interpreter-in-go/lexer"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"github.com/lancelote/writing-an-interpreter-in-go/parser"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Interpreter keeps global bindings and macros between runs, it is not safe
+// for concurrent use.
+type Interpreter struct {
+	runtime  *object.Runtime
+	env      *object.Environment
+	macroEnv *object.Environment
+}
+
+type Option func(*Interpreter)
+
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.runtime.Stdout = w }
+}
+
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.runtime.Stderr = w }
+}
+
+// WithArgs sets command-line arguments returned by `args`.
+func WithArgs(args ...string) Option {
+	return func(i *Interpreter) { i.runtime.Args = args }
+}
+
+// WithExitHandler sets a function called when a script calls `exit`.
+func WithExitHandler(fn func(code int)) Option {
+	return func(i *Interpreter) { i.runtime.OnExit = fn }
+}
+
+// WithStepLimit limits the number of evaluation steps of every run.
+func WithStepLimit(steps int64) Option {
+	return func(i *Interpreter) { i.runtime.MaxSteps = steps }
+}
+
+// WithDepthLimit limits the depth of nested function calls, it can't exceed
+// object.MaxCallDepth.
+func WithDepthLimit(depth int) Option {
+	return func(i *Interpreter) { i.runtime.MaxDepth = depth }
+}
+
+// WithAllocLimit limits the number of bytes allocated by every run.
+func WithAllocLimit(bytes int64) Option {
+	return func(i *Interpreter) { i.runtime.MaxAlloc = bytes }
+}
+
+// WithStrictIndex makes out of range indexing an error instead of null.
+func WithStrictIndex() Option {
+	return func(i *Interpreter) { i.runtime.StrictIndex = true }
+}
+
+// WithFS gives scripts access to the file system, writes are only allowed if
+// it implements object.WriteFS, see OpenDir.
+func WithFS(fsys fs.FS) Option {
+	return func(i *Interpreter) { i.runtime.FS = fsys }
+}
+
+// WithModules allows `import`, modules are looked up in the directories of
+// the file system in the given order, only in its root if none are given.
+func WithModules(fsys fs.FS, dirs ...string) Option {
+	return func(i *Interpreter) {
+		i.runtime.ModuleFS = fsys
+		i.runtime.ModulePath = dirs
+	}
+}
+
+// WithClock sets the source of the current time, e.g. to freeze it in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(i *Interpreter) { i.runtime.Clock = clock }
+}
+
+// WithSeed seeds the random generator of rand_int, shuffle and choice,
+// interpreters with the same seed produce the same sequence of numbers.
+func WithSeed(seed uint64) Option {
+	return func(i *Interpreter) { i.runtime.Rand = rand.New(rand.NewPCG(seed, seed)) }
+}
+
+// WithBuiltins registers host builtins, see Interpreter.Register.
+func WithBuiltins(builtins ...*object.Builtin) Option {
+	return func(i *Interpreter) {
+		for _, builtin := range builtins {
+			i.Register(builtin)
+		}
+	}
+}
+
+func New(options ...Option) *Interpreter {
+	runtime := object.NewRuntime()
+
+	i := &Interpreter{
+		runtime:  runtime,
+		env:      object.NewEnvironmentWithRuntime(runtime),
+		macroEnv: object.NewEnvironmentWithRuntime(runtime),
+	}
+
+	for _, option := range options {
+		option(i)
+	}
+
+	return i
+}
+
+// ParseError lists every syntax error found in the source.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Errors, "; ")
+}
+
+// RuntimeError is a Monkey error object returned from evaluation.
+type RuntimeError struct {
+	Object *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Object.Message
+}
+
+// Unwrap gives access to the cause of an aborted evaluation, e.g. a context
+// error, a limit error or *object.ExitError.
+func (e *RuntimeError) Unwrap() error {
+	return e.Object.Cause
+}
+
+// Run evaluates the source in the global environment and returns the value
+// of the last statement, nil if it doesn't produce any (e.g. `let`).
+func (i *Interpreter) Run(source string) (object.Object, error) {
+	return i.RunContext(context.Background(), source)
+}
+
+// RunContext is Run aborting once ctx is done. A leading `#!` line of the
+// source is ignored, so executable scripts can be run as they are.
+func (i *Interpreter) RunContext(ctx context.Context, source string) (object.Object, error) {
+	p := parser.New(lexer.New(lexer.StripShebang(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+
+	i.runtime.Reset()
+	defer i.useContext(ctx)()
+
+	evaluator.DefineMacros(program, i.macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, i.macroEnv)
+	if err != nil {
+		return nil, &RuntimeError{Object: err}
+	}
+
+	return result(evaluator.Eval(expanded, i.env))
+}
+
+// Call calls a global Monkey function or builtin by name.
+func (i *Interpreter) Call(name string, args ...object.Object) (object.Object, error) {
+	return i.CallContext(context.Background(), name, args...)
+}
+
+func (i *Interpreter) CallContext(ctx context.Context, name string, args ...object.Object) (object.Object, error) {
+	fn, ok := evaluator.Lookup(name, i.env)
+	if !ok {
+		return nil, fmt.Errorf("identifier not found: %s", name)
+	}
+
+	i.runtime.Reset()
+	defer i.useContext(ctx)()
+
+	return result(evaluator.Apply(i.runtime, fn, args))
+}
+
+// Register exposes a host function to scripts of this interpreter only, it
+// shadows a standard builtin with the same name. Arguments are validated
+// against builtin.Params before the function is called.
+func (i *Interpreter) Register(builtin *object.Builtin) {
+	i.runtime.Register(builtin)
+}
+
+// Set binds a global name visible to the following runs.
+func (i *Interpreter) Set(name string, value object.Object) {
+	i.env.Set(name, value)
+}
+
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// useContext makes evaluation abort once ctx is done, the returned function
+// restores the previous context
+func (i *Interpreter) useContext(ctx context.Context) func() {
+	previous := i.runtime.Context
+	i.runtime.Context = ctx
+	return func() { i.runtime.Context = previous }
+}
+
+func result(obj object.Object) (object.Object, error) {
+	if errObj, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Object: errObj}
+	}
+
+	return obj, nil
+}
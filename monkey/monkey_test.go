@@ -0,0 +1,388 @@
+package monkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/lancelote/writing-an-interpreter-in-go/object"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	interpreter := New()
+
+	if _, err := interpreter.Run("let add = fn(x, y) { x + y };"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	evaluated, err := interpreter.Run("add(1, 2)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testIntegerObject(t, evaluated, 3)
+}
+
+func TestRunShebang(t *testing.T) {
+	evaluated, err := New().Run("#!/usr/bin/env monkey\nlet x = \"#!${1 + 1}\"; x")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if evaluated.Inspect() != "#!2" {
+		t.Errorf("want shebang skipped, got %q", evaluated.Inspect())
+	}
+}
+
+func TestRunMacros(t *testing.T) {
+	interpreter := New()
+
+	_, err := interpreter.Run("let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	evaluated, err := interpreter.Run("unless(1 > 2, 10, 20)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testIntegerObject(t, evaluated, 10)
+}
+
+func TestRunErrors(t *testing.T) {
+	interpreter := New()
+
+	_, err := interpreter.Run("let = 5")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("want parse error, got %v", err)
+	}
+
+	_, err = interpreter.Run("1 + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("want runtime error, got %v", err)
+	}
+
+	if err.Error() != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("want type mismatch error, got %q", err.Error())
+	}
+}
+
+func TestUnboundedRecursion(t *testing.T) {
+	// the deadline is long enough for the Go stack to overflow without a cap
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := New().RunContext(ctx, "let f = fn(x) { f(x + 1) }; f(0)")
+	if !errors.Is(err, object.ErrDepthLimit) {
+		t.Errorf("want depth limit error, got %v", err)
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	loop := "let loop = fn() { loop() }; loop();"
+
+	_, err := New(WithStepLimit(1000)).Run(loop)
+	if !errors.Is(err, object.ErrStepLimit) {
+		t.Errorf("want step limit error, got %v", err)
+	}
+
+	_, err = New(WithDepthLimit(100)).Run(loop)
+	if !errors.Is(err, object.ErrDepthLimit) {
+		t.Errorf("want depth limit error, got %v", err)
+	}
+
+	_, err = New(WithAllocLimit(100)).Run(`"a" + "b"; [1, 2, 3, 4, 5, 6, 7, 8]`)
+	if !errors.Is(err, object.ErrAllocLimit) {
+		t.Errorf("want allocation limit error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	spin := "let spin = fn(n) { if (n > 0) { spin(n - 1); spin(n - 1) } }; spin(50);"
+
+	_, err = New().RunContext(ctx, spin)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded error, got %v", err)
+	}
+}
+
+func TestStrictIndex(t *testing.T) {
+	if _, err := New().Run("[1, 2][5]"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	_, err := New(WithStrictIndex()).Run("[1, 2][5]")
+	if err == nil || err.Error() != "index out of range: 5, length 2" {
+		t.Errorf("want index out of range error, got %v", err)
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := OpenDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	interpreter := New(WithFS(fsys))
+
+	result, err := interpreter.Run(`write_file("out.txt", "data"); read_file("out.txt")`)
+	if err != nil || result.Inspect() != "data" {
+		t.Fatalf("want written data, got %v, %v", result, err)
+	}
+
+	written, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil || string(written) != "data" {
+		t.Errorf("want file written to the directory, got %q, %v", written, err)
+	}
+
+	for _, input := range []string{`read_file("link.txt")`, `write_file("link.txt", "x")`} {
+		if _, err := interpreter.Run(input); err == nil {
+			t.Errorf("%s: want error escaping the directory", input)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	frozen := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err := New(WithClock(func() time.Time { return frozen })).Run(`now().format("2006")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result.Inspect() != "2000" {
+		t.Errorf("want frozen time, got %s", result.Inspect())
+	}
+}
+
+func TestArgs(t *testing.T) {
+	result, err := New(WithArgs("a", "b")).Run(`args()`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result.Inspect() != "[a, b]" {
+		t.Errorf("want arguments, got %s", result.Inspect())
+	}
+}
+
+func TestModules(t *testing.T) {
+	var stdout bytes.Buffer
+
+	modules := fstest.MapFS{"lib/greet.mk": {Data: []byte(`puts("loaded"); export let hello = fn(name) { "hello " + name };`)}}
+	interpreter := New(WithModules(modules, "lib"), WithStdout(&stdout))
+
+	// modules are shared between runs like global bindings
+	for i := 0; i < 2; i++ {
+		result, err := interpreter.Run(`(import "greet").hello("monkey")`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if result.Inspect() != "hello monkey" {
+			t.Errorf("want exported function called, got %s", result.Inspect())
+		}
+	}
+
+	if stdout.String() != "loaded\n" {
+		t.Errorf("want module evaluated once, got output %q", stdout.String())
+	}
+}
+
+func TestSeed(t *testing.T) {
+	run := func() string {
+		result, err := New(WithSeed(42)).Run("shuffle(range(20))")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return result.Inspect()
+	}
+
+	if run() != run() {
+		t.Errorf("want the same results for the same seed")
+	}
+}
+
+func TestLimitsArePerRun(t *testing.T) {
+	interpreter := New(WithStepLimit(100))
+
+	for i := 0; i < 10; i++ {
+		if _, err := interpreter.Run("1 + 2 + 3"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+}
+
+func TestIO(t *testing.T) {
+	var stdout bytes.Buffer
+	var code int
+
+	interpreter := New(WithStdout(&stdout), WithExitHandler(func(c int) { code = c }))
+
+	_, err := interpreter.Run(`puts("hello"); exit(4); puts("unreachable")`)
+
+	var exitErr *object.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 4 {
+		t.Errorf("want exit error with code 4, got %v", err)
+	}
+
+	if code != 4 {
+		t.Errorf("want exit handler called with 4, got %d", code)
+	}
+
+	if stdout.String() != "hello\n" {
+		t.Errorf("want %q output, got %q", "hello\n", stdout.String())
+	}
+}
+
+func TestCall(t *testing.T) {
+	interpreter := New()
+
+	if _, err := interpreter.Run("let add = fn(x, y) { x + y };"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	evaluated, err := interpreter.Call("add", &object.Integer{Value: 1}, &object.Integer{Value: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, evaluated, 3)
+
+	evaluated, err = interpreter.Call("len", &object.String{Value: "four"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, evaluated, 4)
+
+	if _, err := interpreter.Call("add", &object.Integer{Value: 1}); err == nil {
+		t.Errorf("want error on wrong number of arguments")
+	}
+
+	if _, err := interpreter.Call("missing"); err == nil {
+		t.Errorf("want error on missing function")
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	interpreter := New()
+	interpreter.Set("answer", &object.Integer{Value: 42})
+
+	evaluated, err := interpreter.Run("let double = answer * 2; double")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, evaluated, 84)
+
+	double, ok := interpreter.Get("double")
+	if !ok {
+		t.Fatalf("`double` is not defined")
+	}
+	testIntegerObject(t, double, 84)
+}
+
+func TestRegister(t *testing.T) {
+	greet := &object.Builtin{
+		Name:   "greet",
+		Params: []object.ObjectType{object.STRING_OBJ, object.INTEGER_OBJ},
+		Doc:    "Greets somebody a number of times.",
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			name := args[0].(*object.String).Value
+			times := args[1].(*object.Integer).Value
+
+			greeting := ""
+			for range times {
+				greeting += "hello " + name + "! "
+			}
+
+			return &object.String{Value: greeting}
+		},
+	}
+
+	interpreter := New(WithBuiltins(greet))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`greet("monkey", 2)`, "hello monkey! hello monkey! "},
+		{`greet("monkey")`, "`greet` accepts 2 arguments, got 1"},
+		{`greet(1, 2)`, "argument 1 to `greet` should be STRING, got INTEGER"},
+		{`help(greet)`, "greet(STRING, INTEGER)\nGreets somebody a number of times."},
+	}
+
+	for _, tt := range tests {
+		evaluated, err := interpreter.Run(tt.input)
+		if err != nil {
+			evaluated = &object.String{Value: err.Error()}
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("want string, got %T", evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("want %q, got %q", tt.expected, str.Value)
+		}
+	}
+
+	if _, err := New().Run(`greet("monkey", 1)`); err == nil {
+		t.Errorf("builtin registered in another interpreter is visible")
+	}
+}
+
+func TestRegisterShadowsStandardBuiltin(t *testing.T) {
+	interpreter := New()
+	interpreter.Register(&object.Builtin{
+		Name:     "len",
+		Params:   []object.ObjectType{object.ANY_OBJ},
+		Variadic: true,
+		Fn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			return &object.Integer{Value: int64(len(args))}
+		},
+	})
+
+	evaluated, err := interpreter.Run(`len(1, "two", [3])`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testIntegerObject(t, evaluated, 3)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	t.Helper()
+
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("want integer, got %T", obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("want %d, got %d", expected, result.Value)
+		return false
+	}
+
+	return true
+}
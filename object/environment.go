@@ -1,21 +1,31 @@
 package object
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store   map[string]Object
+	outer   *Environment
+	runtime *Runtime
+	exports []string
 }
 
 func NewEnvironment() *Environment {
+	return NewEnvironmentWithRuntime(NewRuntime())
+}
+
+func NewEnvironmentWithRuntime(runtime *Runtime) *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, outer: nil, runtime: runtime}
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
+	env := NewEnvironmentWithRuntime(outer.runtime)
 	env.outer = outer
 	return env
 }
 
+func (e *Environment) Runtime() *Runtime {
+	return e.runtime
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -28,3 +38,22 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// Enclosed reports whether the environment belongs to a function call.
+func (e *Environment) Enclosed() bool {
+	return e.outer != nil
+}
+
+// Export marks a binding to be included into Exports.
+func (e *Environment) Export(name string) {
+	e.exports = append(e.exports, name)
+}
+
+// Exports returns the current values of exported bindings.
+func (e *Environment) Exports() map[string]Object {
+	exports := make(map[string]Object, len(e.exports))
+	for _, name := range e.exports {
+		exports[name] = e.store[name]
+	}
+	return exports
+}
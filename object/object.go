@@ -2,10 +2,13 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"github.com/lancelote/writing-an-interpreter-in-go/ast"
 	"hash/fnv"
+	"regexp"
 	"strings"
+	"time"
 )
 
 type ObjectType string
@@ -23,6 +26,10 @@ const (
 	HASH_OBJ         = "HASH"
 	QUOTE_OBJ        = "QUOTE"
 	MACRO_OBJ        = "MACRO"
+	HOST_OBJ         = "HOST"
+	REGEX_OBJ        = "REGEX"
+	TIME_OBJ         = "TIME"
+	MODULE_OBJ       = "MODULE"
 )
 
 type Object interface {
@@ -31,6 +38,7 @@ type Object interface {
 }
 
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
@@ -39,6 +47,84 @@ type HashKey struct {
 	Value uint64
 }
 
+// CanHash reports whether the object can be used as a hash key, arrays can
+// be used if all of their elements can.
+func CanHash(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Array:
+		for _, e := range obj.Elements {
+			if !CanHash(e) {
+				return false
+			}
+		}
+		return true
+	default:
+		_, ok := obj.(Hashable)
+		return ok
+	}
+}
+
+// Equal compares objects structurally: scalars by value, arrays and hashes
+// by their content and everything else by identity.
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+
+	case *Integer:
+		b, ok := b.(*Integer)
+		return ok && a.Value == b.Value
+
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+
+		for i := range a.Elements {
+			if !Equal(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+
+		for _, pair := range a.pairs {
+			value, ok := b.Get(pair.Key.(Hashable))
+			if !ok || !Equal(pair.Value, value) {
+				return false
+			}
+		}
+		return true
+
+	case *Regex:
+		b, ok := b.(*Regex)
+		return ok && a.Value.String() == b.Value.String()
+
+	case *Time:
+		b, ok := b.(*Time)
+		return ok && a.Value.Equal(b.Value)
+
+	default:
+		return a == b
+	}
+}
+
 type Integer struct {
 	Value int64
 }
@@ -68,10 +154,14 @@ func (s *String) Inspect() string {
 }
 
 func (s *String) HashKey() HashKey {
-	h := fnv.New64a()
-	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: hashString(s.Value)}
+}
 
-	return HashKey{Type: s.Type(), Value: h.Sum64()}
+// hashString is a variable to let tests force collisions
+var hashString = func(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
 }
 
 type Boolean struct {
@@ -122,6 +212,7 @@ func (rv *ReturnValue) Inspect() string {
 
 type Error struct {
 	Message string
+	Cause   error // set when evaluation was aborted by the runtime
 }
 
 func (e *Error) Type() ObjectType {
@@ -188,10 +279,22 @@ func (m *Macro) Inspect() string {
 	return out.String()
 }
 
-type BuiltinFunction func(args ...Object) Object
+type BuiltinFunction func(runtime *Runtime, args ...Object) Object
+
+// ANY_OBJ is a pseudo type used in builtin signatures to accept any object.
+const ANY_OBJ = "ANY"
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Name string
+	Fn   BuiltinFunction
+
+	// Params declares the types of expected arguments, arguments are checked
+	// before Fn is called unless Params is nil. If Variadic is set the last
+	// parameter accepts any number of arguments, with no parameters any
+	// number of arguments of any type is accepted.
+	Params   []ObjectType
+	Variadic bool
+	Doc      string
 }
 
 func (b *Builtin) Type() ObjectType {
@@ -199,7 +302,89 @@ func (b *Builtin) Type() ObjectType {
 }
 
 func (b *Builtin) Inspect() string {
-	return "builtin function"
+	if b.Name == "" {
+		return "builtin function"
+	}
+	return "builtin " + b.Signature()
+}
+
+// Signature describes the builtin, e.g. `split(STRING, STRING...)`.
+func (b *Builtin) Signature() string {
+	if b.Params == nil || b.acceptsAny() {
+		return b.Name + "(...)"
+	}
+
+	params := []string{}
+	for _, p := range b.Params {
+		params = append(params, string(p))
+	}
+
+	if b.Variadic && len(params) > 0 {
+		params[len(params)-1] += "..."
+	}
+
+	return b.Name + "(" + strings.Join(params, ", ") + ")"
+}
+
+// CheckArgs validates arguments against Params and returns a message
+// describing the first mismatch, or an empty string.
+func (b *Builtin) CheckArgs(args []Object) string {
+	if b.Params == nil || b.acceptsAny() {
+		return ""
+	}
+
+	count := len(b.Params)
+	if b.Variadic {
+		if len(args) < count-1 {
+			return fmt.Sprintf("`%s` accepts at least %s, got %d", b.Name, arguments(count-1), len(args))
+		}
+	} else if len(args) != count {
+		return fmt.Sprintf("`%s` accepts %s, got %d", b.Name, arguments(count), len(args))
+	}
+
+	for i, arg := range args {
+		want := b.Params[min(i, count-1)]
+		if want != ANY_OBJ && arg.Type() != want {
+			return fmt.Sprintf("argument %d to `%s` should be %s, got %s", i+1, b.Name, want, arg.Type())
+		}
+	}
+
+	return ""
+}
+
+// acceptsAny reports whether a variadic builtin declares no parameters, it
+// accepts any arguments then
+func (b *Builtin) acceptsAny() bool {
+	return b.Variadic && len(b.Params) == 0
+}
+
+// Bind returns a method: a builtin calling this one with the receiver as the
+// first argument followed by the rest of arguments.
+func (b *Builtin) Bind(receiver Object) *Builtin {
+	bound := &Builtin{
+		Name:     b.Name,
+		Variadic: b.Variadic,
+		Doc:      b.Doc,
+		Fn: func(runtime *Runtime, args ...Object) Object {
+			return b.Fn(runtime, append([]Object{receiver}, args...)...)
+		},
+	}
+
+	switch {
+	case b.Variadic && len(b.Params) == 1:
+		bound.Params = b.Params // receiver is one of variadic arguments
+	case len(b.Params) > 0:
+		bound.Params = b.Params[1:]
+	}
+
+	return bound
+}
+
+func arguments(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
 }
 
 type Array struct {
@@ -210,6 +395,21 @@ func (a *Array) Type() ObjectType {
 	return ARRAY_OBJ
 }
 
+// HashKey combines hash keys of elements, it should only be used if CanHash
+// reports the array as hashable.
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+
+	for _, e := range a.Elements {
+		key := e.(Hashable).HashKey()
+
+		h.Write([]byte(key.Type))
+		binary.Write(h, binary.LittleEndian, key.Value)
+	}
+
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
 func (a *Array) Inspect() string {
 	var out bytes.Buffer
 
@@ -230,19 +430,68 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash keeps pairs in insertion order, the zero value is an empty hash.
+//
+// Different keys may share a HashKey, so every bucket lists positions of all
+// pairs with the same HashKey and keys are compared to find the right one.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	buckets map[HashKey][]int
+	pairs   []HashPair
 }
 
 func (h *Hash) Type() ObjectType {
 	return HASH_OBJ
 }
 
+// Set adds the pair to the end of the hash or replaces the value in place if
+// the key is already present.
+func (h *Hash) Set(key Hashable, value Object) {
+	hashed := key.HashKey()
+
+	if i, ok := h.find(hashed, key); ok {
+		h.pairs[i].Value = value
+		return
+	}
+
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]int)
+	}
+
+	h.buckets[hashed] = append(h.buckets[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	i, ok := h.find(key.HashKey(), key)
+	if !ok {
+		return nil, false
+	}
+	return h.pairs[i].Value, true
+}
+
+func (h *Hash) find(hashed HashKey, key Hashable) (int, bool) {
+	for _, i := range h.buckets[hashed] {
+		if Equal(h.pairs[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (h *Hash) Len() int {
+	return len(h.pairs)
+}
+
+// Pairs returns pairs in insertion order, the slice must not be modified.
+func (h *Hash) Pairs() []HashPair {
+	return h.pairs
+}
+
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -253,6 +502,47 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// Regex is a compiled regular expression, see regexp for the syntax.
+type Regex struct {
+	Value *regexp.Regexp
+}
+
+func (r *Regex) Type() ObjectType {
+	return REGEX_OBJ
+}
+
+func (r *Regex) Inspect() string {
+	return "/" + r.Value.String() + "/"
+}
+
+// Time is an instant with a location used for formatting.
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() ObjectType {
+	return TIME_OBJ
+}
+
+func (t *Time) Inspect() string {
+	return t.Value.Format(time.RFC3339Nano)
+}
+
+// Module holds exported bindings of an imported module, they are accessed as
+// members, e.g. `math.add`.
+type Module struct {
+	Name    string // resolved path of the module file
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType {
+	return MODULE_OBJ
+}
+
+func (m *Module) Inspect() string {
+	return "module " + m.Name
+}
+
 type Quote struct {
 	Node ast.Node
 }
@@ -264,3 +554,29 @@ func (q *Quote) Type() ObjectType {
 func (q *Quote) Inspect() string {
 	return "QUOTE(" + q.Node.String() + ")"
 }
+
+// Host carries an arbitrary Go value provided by the host program through
+// Monkey code, methods are builtins called with the Host as the first
+// argument.
+type Host struct {
+	TypeName string
+	Value    any
+	Methods  map[string]*Builtin
+}
+
+func (h *Host) Type() ObjectType {
+	return HOST_OBJ
+}
+
+func (h *Host) Inspect() string {
+	return "<" + h.TypeName + ">"
+}
+
+// Method returns the method bound to the receiver.
+func (h *Host) Method(name string) (*Builtin, bool) {
+	method, ok := h.Methods[name]
+	if !ok {
+		return nil, false
+	}
+	return method.Bind(h), true
+}
@@ -1,6 +1,7 @@
 package object
 
 import (
+	"regexp"
 	"testing"
 )
 
@@ -23,3 +24,195 @@ func TestStringHashKey(t *testing.T) {
 		t.Error("strings with different content but same hash key")
 	}
 }
+
+func TestHashOrder(t *testing.T) {
+	hash := &Hash{}
+
+	hash.Set(&String{Value: "b"}, &Integer{Value: 1})
+	hash.Set(&Integer{Value: 1}, &Integer{Value: 2})
+	hash.Set(&String{Value: "a"}, &Integer{Value: 3})
+	hash.Set(&String{Value: "b"}, &Integer{Value: 4})
+
+	if hash.Len() != 3 {
+		t.Fatalf("want 3 pairs, got %d", hash.Len())
+	}
+
+	expected := "{b: 4, 1: 2, a: 3}"
+	if hash.Inspect() != expected {
+		t.Errorf("want %q, got %q", expected, hash.Inspect())
+	}
+
+	value, ok := hash.Get(&String{Value: "a"})
+	if !ok || value.Inspect() != "3" {
+		t.Errorf("want 3 for key a, got %v", value)
+	}
+
+	if _, ok := hash.Get(&String{Value: "c"}); ok {
+		t.Errorf("want no value for missing key")
+	}
+}
+
+func TestHashCollisions(t *testing.T) {
+	original := hashString
+	hashString = func(string) uint64 { return 42 }
+	defer func() { hashString = original }()
+
+	foo := &String{Value: "foo"}
+	bar := &String{Value: "bar"}
+
+	if foo.HashKey() != bar.HashKey() {
+		t.Fatalf("want colliding hash keys")
+	}
+
+	hash := &Hash{}
+	hash.Set(foo, &Integer{Value: 1})
+	hash.Set(bar, &Integer{Value: 2})
+	hash.Set(&Integer{Value: 42}, &Integer{Value: 3})
+
+	tests := []struct {
+		key      Hashable
+		expected int64
+	}{
+		{&String{Value: "foo"}, 1},
+		{&String{Value: "bar"}, 2},
+		{&Integer{Value: 42}, 3},
+	}
+
+	for _, tt := range tests {
+		value, ok := hash.Get(tt.key)
+		if !ok {
+			t.Errorf("no value for key %s", tt.key.Inspect())
+			continue
+		}
+
+		if value.(*Integer).Value != tt.expected {
+			t.Errorf("want %d for key %s, got %s", tt.expected, tt.key.Inspect(), value.Inspect())
+		}
+	}
+
+	if _, ok := hash.Get(&String{Value: "baz"}); ok {
+		t.Errorf("want no value for colliding missing key")
+	}
+
+	hash.Set(&String{Value: "bar"}, &Integer{Value: 20})
+
+	if hash.Len() != 3 {
+		t.Errorf("want 3 pairs, got %d", hash.Len())
+	}
+
+	expected := "{foo: 1, bar: 20, 42: 3}"
+	if hash.Inspect() != expected {
+		t.Errorf("want %q, got %q", expected, hash.Inspect())
+	}
+}
+
+func TestHashKeyTypes(t *testing.T) {
+	hash := &Hash{}
+
+	// same hash key value, different types
+	hash.Set(&Integer{Value: 1}, &String{Value: "integer"})
+	hash.Set(&Boolean{Value: true}, &String{Value: "boolean"})
+
+	if hash.Len() != 2 {
+		t.Fatalf("want 2 pairs, got %d", hash.Len())
+	}
+
+	value, _ := hash.Get(&Integer{Value: 1})
+	if value.Inspect() != "integer" {
+		t.Errorf("want integer, got %s", value.Inspect())
+	}
+
+	value, _ = hash.Get(&Boolean{Value: true})
+	if value.Inspect() != "boolean" {
+		t.Errorf("want boolean, got %s", value.Inspect())
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	b := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	c := &Array{Elements: []Object{&String{Value: "a"}, &Integer{Value: 1}}}
+	d := &Array{Elements: []Object{&String{Value: "1"}, &String{Value: "a"}}}
+
+	if a.HashKey() != b.HashKey() {
+		t.Error("arrays with same content but different hash key")
+	}
+
+	if a.HashKey() == c.HashKey() || a.HashKey() == d.HashKey() {
+		t.Error("arrays with different content but same hash key")
+	}
+
+	if !CanHash(a) {
+		t.Error("array of hashable elements is not hashable")
+	}
+
+	if CanHash(&Array{Elements: []Object{a, &Array{Elements: []Object{&Hash{}}}}}) {
+		t.Error("array with a hash inside is hashable")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	hash := func(pairs ...Object) *Hash {
+		h := &Hash{}
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i].(Hashable), pairs[i+1])
+		}
+		return h
+	}
+
+	one := &Integer{Value: 1}
+	fn := &Function{}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{one, &Integer{Value: 1}, true},
+		{one, &String{Value: "1"}, false},
+		{&Null{}, &Null{}, true},
+		{&Null{}, &Boolean{Value: false}, false},
+		{&Array{Elements: []Object{one}}, &Array{Elements: []Object{&Integer{Value: 1}}}, true},
+		{&Array{Elements: []Object{one}}, &Array{}, false},
+		{hash(one, one, &String{Value: "x"}, one), hash(&String{Value: "x"}, one, one, one), true},
+		{hash(one, one), hash(one, &Integer{Value: 2}), false},
+		{fn, fn, true},
+		{fn, &Function{}, false},
+		{&Regex{Value: regexp.MustCompile("a+")}, &Regex{Value: regexp.MustCompile("a+")}, true},
+		{&Regex{Value: regexp.MustCompile("a+")}, &Regex{Value: regexp.MustCompile("a*")}, false},
+	}
+
+	for _, tt := range tests {
+		if Equal(tt.a, tt.b) != tt.expected {
+			t.Errorf("want Equal(%s, %s) to be %t", tt.a.Inspect(), tt.b.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestBuiltinCheckArgs(t *testing.T) {
+	one, str := &Integer{Value: 1}, &String{Value: "a"}
+
+	tests := []struct {
+		builtin  *Builtin
+		args     []Object
+		expected string
+	}{
+		{&Builtin{Name: "f"}, []Object{one, str}, ""},
+		{&Builtin{Name: "f", Params: []ObjectType{}}, []Object{}, ""},
+		{&Builtin{Name: "f", Params: []ObjectType{}}, []Object{one}, "`f` accepts 0 arguments, got 1"},
+		{&Builtin{Name: "f", Params: []ObjectType{}, Variadic: true}, []Object{}, ""},
+		{&Builtin{Name: "f", Params: []ObjectType{}, Variadic: true}, []Object{one, str}, ""},
+		{&Builtin{Name: "f", Params: []ObjectType{INTEGER_OBJ}, Variadic: true}, []Object{one, one}, ""},
+		{&Builtin{Name: "f", Params: []ObjectType{INTEGER_OBJ}, Variadic: true}, []Object{one, str}, "argument 2 to `f` should be INTEGER, got STRING"},
+		{&Builtin{Name: "f", Params: []ObjectType{STRING_OBJ, INTEGER_OBJ}, Variadic: true}, []Object{}, "`f` accepts at least 1 argument, got 0"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.builtin.CheckArgs(tt.args); got != tt.expected {
+			t.Errorf("%s with %d arguments: want %q, got %q", tt.builtin.Signature(), len(tt.args), tt.expected, got)
+		}
+	}
+
+	if signature := (&Builtin{Name: "f", Params: []ObjectType{}, Variadic: true}).Signature(); signature != "f(...)" {
+		t.Errorf("want f(...), got %s", signature)
+	}
+}
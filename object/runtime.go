@@ -0,0 +1,288 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrStepLimit  = errors.New("step limit exceeded")
+	ErrDepthLimit = errors.New("call depth limit exceeded")
+	ErrAllocLimit = errors.New("allocation limit exceeded")
+)
+
+// MaxCallDepth caps the depth of nested function calls even if MaxDepth is
+// not set: deeper recursion overflows the Go stack, which kills the process
+// before a context deadline could stop it.
+const MaxCallDepth = 10000
+
+// approximate sizes in bytes used for allocation accounting
+const (
+	referenceSize = 16                   // interface value
+	hashPairSize  = 2*referenceSize + 24 // key, value and hash key
+)
+
+// WriteFS is a file system scripts may also write to.
+type WriteFS interface {
+	fs.FS
+	WriteFile(name string, data []byte) error
+}
+
+// ExitError is the cause of an error unwinding evaluation after `exit` call.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Runtime is the execution state shared by every environment of a program:
+// cancellation, limits, I/O and the resources used so far.
+type Runtime struct {
+	Context  context.Context
+	MaxSteps int64 // 0 means unlimited
+	MaxDepth int   // 0 or more than MaxCallDepth means MaxCallDepth
+	MaxAlloc int64 // bytes, 0 means unlimited
+
+	// StrictIndex makes out of range array and string indexing an error
+	// instead of null
+	StrictIndex bool
+
+	// output of builtins, nil writers discard everything written to them
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// OnExit is called by `exit` before evaluation is unwound, optional
+	OnExit func(code int)
+
+	// FS is the only file system scripts can access, files are not available
+	// if nil and read-only unless it implements WriteFS
+	FS fs.FS
+
+	// Args are command-line arguments of the script returned by `args`
+	Args []string
+
+	// ModuleFS holds modules loaded by `import`, imports are not allowed if
+	// nil. Modules are looked up in directories of ModulePath in order, only
+	// in its root if ModulePath is empty.
+	ModuleFS   fs.FS
+	ModulePath []string
+
+	// Clock returns the current time for `now`, time.Now is used if nil
+	Clock func() time.Time
+
+	// Rand is the source of rand_int, shuffle and choice, a randomly seeded
+	// one is created on first use if nil
+	Rand *rand.Rand
+
+	// builtins registered by the host, they shadow the standard ones
+	builtins map[string]*Builtin
+
+	modules   map[string]*Module // loaded modules by resolved name
+	importing []string           // modules being loaded, the innermost last
+
+	steps     int64
+	depth     int
+	allocated int64
+}
+
+func NewRuntime() *Runtime {
+	return &Runtime{
+		Context: context.Background(),
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+	}
+}
+
+// Step accounts for a single evaluation step, returns an error if evaluation
+// has to be aborted.
+func (r *Runtime) Step() *Error {
+	r.steps++
+	if r.MaxSteps > 0 && r.steps > r.MaxSteps {
+		return newAbortError(ErrStepLimit)
+	}
+
+	if r.Context != nil {
+		select {
+		case <-r.Context.Done():
+			return newAbortError(r.Context.Err())
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Enter accounts for a function call, every successful Enter should be paired
+// with Leave once the call returns.
+func (r *Runtime) Enter() *Error {
+	limit := MaxCallDepth
+	if r.MaxDepth > 0 {
+		limit = min(r.MaxDepth, MaxCallDepth)
+	}
+
+	if r.depth >= limit {
+		return newAbortError(ErrDepthLimit)
+	}
+
+	r.depth++
+	return nil
+}
+
+func (r *Runtime) Leave() {
+	r.depth--
+}
+
+// Allocate accounts for a newly created array, string or hash, other objects
+// are not accounted for.
+func (r *Runtime) Allocate(obj Object) *Error {
+	return r.AllocateBytes(sizeOf(obj))
+}
+
+// AllocateBytes accounts for n bytes. Builtins producing objects of a size
+// known in advance call it before building them, the object may be huge, so
+// it's checked first and then shouldn't be passed to Allocate.
+func (r *Runtime) AllocateBytes(n int64) *Error {
+	r.allocated += n
+	if r.MaxAlloc > 0 && (r.allocated > r.MaxAlloc || n < 0) {
+		return newAbortError(ErrAllocLimit)
+	}
+
+	return nil
+}
+
+// AllocateArray accounts for an array of the given length, see AllocateBytes.
+func (r *Runtime) AllocateArray(length int64) *Error {
+	return r.AllocateBytes(length * referenceSize)
+}
+
+// Register makes the builtin available to every program using the runtime
+// under its name.
+func (r *Runtime) Register(builtin *Builtin) {
+	if r.builtins == nil {
+		r.builtins = make(map[string]*Builtin)
+	}
+	r.builtins[builtin.Name] = builtin
+}
+
+func (r *Runtime) Builtin(name string) (*Builtin, bool) {
+	builtin, ok := r.builtins[name]
+	return builtin, ok
+}
+
+// Exit returns an error unwinding evaluation with the given exit code.
+func (r *Runtime) Exit(code int) *Error {
+	if r.OnExit != nil {
+		r.OnExit(code)
+	}
+
+	return newAbortError(&ExitError{Code: code})
+}
+
+// Module returns a module loaded before by its resolved name, every module is
+// evaluated once and shared by all of its importers.
+func (r *Runtime) Module(name string) (*Module, bool) {
+	module, ok := r.modules[name]
+	return module, ok
+}
+
+// BeginImport marks the module as being loaded, it returns an error if the
+// module is already being loaded, i.e. imports form a cycle. Every successful
+// BeginImport should be paired with EndImport.
+func (r *Runtime) BeginImport(name string) *Error {
+	for i, importing := range r.importing {
+		if importing == name {
+			cycle := append(r.importing[i:len(r.importing):len(r.importing)], name)
+			return &Error{Message: "import cycle: " + strings.Join(cycle, " -> ")}
+		}
+	}
+
+	r.importing = append(r.importing, name)
+	return nil
+}
+
+// EndImport finishes loading of the innermost module, it is cached unless
+// it's nil, i.e. loading failed.
+func (r *Runtime) EndImport(module *Module) {
+	name := r.importing[len(r.importing)-1]
+	r.importing = r.importing[:len(r.importing)-1]
+
+	if module == nil {
+		return
+	}
+
+	if r.modules == nil {
+		r.modules = make(map[string]*Module)
+	}
+	r.modules[name] = module
+}
+
+func (r *Runtime) Now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock()
+}
+
+func (r *Runtime) Random() *rand.Rand {
+	if r.Rand == nil {
+		r.Rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return r.Rand
+}
+
+func (r *Runtime) Out() io.Writer {
+	if r.Stdout == nil {
+		return io.Discard
+	}
+	return r.Stdout
+}
+
+func (r *Runtime) Err() io.Writer {
+	if r.Stderr == nil {
+		return io.Discard
+	}
+	return r.Stderr
+}
+
+func (r *Runtime) Steps() int64 {
+	return r.steps
+}
+
+// Allocated returns the total number of bytes allocated so far, memory is
+// never given back even if objects are no longer reachable.
+func (r *Runtime) Allocated() int64 {
+	return r.allocated
+}
+
+// Reset clears the resources used so far, limits are kept.
+func (r *Runtime) Reset() {
+	r.steps = 0
+	r.depth = 0
+	r.allocated = 0
+}
+
+func sizeOf(obj Object) int64 {
+	switch obj := obj.(type) {
+	case *String:
+		return int64(len(obj.Value))
+	case *Array:
+		return int64(len(obj.Elements)) * referenceSize
+	case *Hash:
+		return int64(obj.Len()) * hashPairSize
+	default:
+		return 0
+	}
+}
+
+func newAbortError(cause error) *Error {
+	return &Error{Message: "evaluation aborted: " + cause.Error(), Cause: cause}
+}
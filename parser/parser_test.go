@@ -404,6 +404,50 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a.b.c(d) * -e.f",
+			"(((a.b).c)(d) * (-(e.f)))",
+		},
+		{
+			"a.b[1].c",
+			"(((a.b)[1]).c)",
+		},
+		{
+			"a[1:2] + a[:b] + a[c + 1:] + a[:]",
+			"((((a[1:2]) + (a[:b])) + (a[(c + 1):])) + (a[:]))",
+		},
+		{
+			"a[1:2:3] + a[::-1] + a[:b:] + a[1::c]",
+			"((((a[1:2:3]) + (a[::(-1)])) + (a[:b])) + (a[1::c]))",
+		},
+		{
+			"[...a, b, ...c + d]",
+			"[...a, b, ...(c + d)]",
+		},
+		{
+			"add(...a[1:], ...f(b))",
+			"add(...(a[1:]), ...f(b))",
+		},
+		{
+			"{...a, 1: 2, ...b}",
+			"{...a, 1:2, ...b}",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			`"a" in b == c in d + e`,
+			"((a in b) == (c in (d + e)))",
+		},
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"!a && b || c < d",
+			"(((!a) && b) || (c < d))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -573,6 +617,59 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStringExpression(t *testing.T) {
+	input := `"a ${b + 1} c ${d["e"]}";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	checkParseErrors(t, p)
+
+	assertStatementCount(t, program.Statements, 1)
+
+	stmt := assertExpressionStatement(t, program.Statements[0])
+
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("expected interpolated string, got %T", stmt.Expression)
+	}
+
+	expected := []string{"a ", "(b + 1)", " c ", "(d[e])"}
+	if len(str.Parts) != len(expected) {
+		t.Fatalf("want %d parts, got %d", len(expected), len(str.Parts))
+	}
+
+	for i, part := range str.Parts {
+		if part.String() != expected[i] {
+			t.Errorf("want part %q, got %q", expected[i], part.String())
+		}
+	}
+
+	if str.String() != "a ${(b + 1)} c ${(d[e])}" {
+		t.Errorf("unexpected string %q", str.String())
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	tests := []string{
+		`"a ${b"`,
+		`"a ${"b`,
+		`"a ${}"`,
+		`"a ${b c}"`,
+		`"a ${let}"`,
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: want parse error", input)
+		}
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -650,6 +747,94 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingMemberExpressions(t *testing.T) {
+	input := "db.query(1)"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	checkParseErrors(t, p)
+
+	assertStatementCount(t, program.Statements, 1)
+
+	stmt := assertExpressionStatement(t, program.Statements[0])
+
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("want call expression, got %T", stmt.Expression)
+	}
+
+	memberExp, ok := call.Function.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("want member expression, got %T", call.Function)
+	}
+
+	if !testIdentifier(t, memberExp.Object, "db") {
+		return
+	}
+
+	if !testIdentifier(t, memberExp.Member, "query") {
+		return
+	}
+}
+
+func TestParsingMemberExpressionErrors(t *testing.T) {
+	l := lexer.New("db.1")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("want parse error for non-identifier member")
+	}
+}
+
+func TestParsingImportExport(t *testing.T) {
+	input := `export let math = import "lib/math";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	checkParseErrors(t, p)
+
+	assertStatementCount(t, program.Statements, 1)
+
+	stmt := program.Statements[0]
+	if !testLetStatement(t, stmt, "math") {
+		return
+	}
+
+	let := stmt.(*ast.LetStatement)
+	if !let.Exported {
+		t.Errorf("want exported let statement")
+	}
+
+	imp, ok := let.Value.(*ast.ImportExpression)
+	if !ok {
+		t.Fatalf("want import expression, got %T", let.Value)
+	}
+
+	if imp.Path != "lib/math" {
+		t.Errorf("want path lib/math, got %q", imp.Path)
+	}
+
+	if program.String() != input {
+		t.Errorf("want %q, got %q", input, program.String())
+	}
+}
+
+func TestParsingImportExportErrors(t *testing.T) {
+	for _, input := range []string{`import lib`, `export fn() {}`, `let path = "lib"; import path`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: want parse error", input)
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -678,15 +863,19 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("want string key, got %T", key)
+			t.Errorf("want string key, got %T", pair.Key)
 		}
 
 		expectedValue := expected[literal.String()]
 
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
+	}
+
+	if hash.String() != "{one:1, two:2, three:3}" {
+		t.Errorf("want pairs in source order, got %q", hash.String())
 	}
 }
 
@@ -747,10 +936,10 @@ func TestParsingHashLiteralsWitExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("want string key, got %T", key)
+			t.Errorf("want string key, got %T", pair.Key)
 			continue
 		}
 
@@ -759,7 +948,7 @@ func TestParsingHashLiteralsWitExpressions(t *testing.T) {
 			t.Errorf("no test function for key %q found", literal.String())
 		}
 
-		testFunc(value)
+		testFunc(pair.Value)
 	}
 }
 
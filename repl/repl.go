@@ -2,42 +2,43 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"github.com/lancelote/writing-an-interpreter-in-go/evaluator"
-	"github.com/lancelote/writing-an-interpreter-in-go/lexer"
+	"github.com/lancelote/writing-an-interpreter-in-go/monkey"
 	"github.com/lancelote/writing-an-interpreter-in-go/object"
-	"github.com/lancelote/writing-an-interpreter-in-go/parser"
 	"io"
 )
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
+// Start runs the REPL until the input is exhausted or `exit` is called,
+// returns the exit code.
+func Start(in io.Reader, out io.Writer) int {
 	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
-	macroEnv := object.NewEnvironment()
+	interpreter := monkey.New(monkey.WithStdout(out), monkey.WithStderr(out))
 
 	for {
 		fmt.Fprintf(out, PROMPT)
 		scanned := scanner.Scan()
 		if !scanned {
-			return
+			return 0
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
+		evaluated, err := interpreter.Run(scanner.Text())
 
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
-			continue
-		}
+		var parseErr *monkey.ParseError
+		var runtimeErr *monkey.RuntimeError
+		var exitErr *object.ExitError
 
-		evaluator.DefineMacros(program, macroEnv)
-		expanded := evaluator.ExpandMacros(program, macroEnv)
+		switch {
+		case errors.As(err, &exitErr):
+			return exitErr.Code
+		case errors.As(err, &parseErr):
+			printParseErrors(out, parseErr.Errors)
+		case errors.As(err, &runtimeErr):
+			evaluated = runtimeErr.Object
+		}
 
-		evaluated := evaluator.Eval(expanded, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")